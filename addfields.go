@@ -0,0 +1,313 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// checkboxOnState is the export value of added checkboxes.
+const checkboxOnState = "Yes"
+
+// FieldSpec describes a form field added to a PDF.
+type FieldSpec struct {
+	// Type is the field type: Text, Button for a checkbox or Choice for a combo box.
+	Type string
+	// Name is the fully qualified field name. Missing parent fields are created.
+	Name string
+	// Page is the page number starting with 1.
+	Page int
+	// Rect is the widget rectangle in PDF user space units.
+	Rect Rect
+	// Tooltip is the alternate field name shown as tooltip.
+	Tooltip string
+	// FontSize is the font size of text and choice fields.
+	// Zero fits the text into the field.
+	FontSize float64
+	// Multiline allows multiple lines of text.
+	Multiline bool
+	// Required marks the field as required.
+	Required bool
+	// Options are the options of choice fields.
+	Options []string
+}
+
+// AddFields adds the fields to the input PDF file and writes the result to
+// the destination file. This patches form PDFs missing fields before filling.
+// Checkboxes are checked with the export value "Yes" or true.
+// The fields use the Helvetica font and have no border.
+// An existing destination file is replaced. No external utility is required.
+func AddFields(inputPDFFile, destPDFFile string, fields []FieldSpec) error {
+	return AddFieldsContext(context.Background(), inputPDFFile, destPDFFile, fields)
+}
+
+// AddFieldsContext is like AddFields, but checks the context
+// for cancellation before processing.
+func AddFieldsContext(ctx context.Context, inputPDFFile, destPDFFile string, fields []FieldSpec) (err error) {
+	// Get the absolute paths.
+	destPDFFile, err = filepath.Abs(destPDFFile)
+	if err != nil {
+		return fmt.Errorf("failed to create the absolute path: %w", err)
+	}
+	inputs, err := absExistingFiles([]string{inputPDFFile})
+	if err != nil {
+		return err
+	}
+
+	err = ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	// Create a temporary directory and remove it on defer again.
+	tmpDir, err := createTempDir("")
+	if err != nil {
+		return err
+	}
+	defer removeTempDir(tmpDir)
+
+	outputFile := filepath.Join(tmpDir, "output.pdf")
+	err = addFields(inputs[0], outputFile, fields)
+	if err != nil {
+		return err
+	}
+
+	return writeDestFile(outputFile, destPDFFile, true)
+}
+
+func addFields(inputFile, outputFile string, fields []FieldSpec) error {
+	ctx, err := readPdfcpuContext(inputFile, "")
+	if err != nil {
+		return err
+	}
+
+	err = insertFields(ctx, fields)
+	if err != nil {
+		return err
+	}
+
+	return writePdfcpuContext(ctx, outputFile)
+}
+
+// insertFields adds the fields to the document.
+func insertFields(ctx *model.Context, fields []FieldSpec) error {
+	form, err := ensureAcroForm(ctx)
+	if err != nil {
+		return err
+	}
+
+	nodes := make(map[string]*fieldNode)
+	err = collectFieldNodes(ctx, form, nodes)
+	if err != nil {
+		return fmt.Errorf("failed to read form fields: %w", err)
+	}
+
+	for _, f := range fields {
+		if f.Name == "" || strings.Contains("."+f.Name+".", "..") {
+			return fmt.Errorf("invalid field name: '%s'", f.Name)
+		} else if nodes[f.Name] != nil {
+			return fmt.Errorf("field '%s' already exists", f.Name)
+		} else if f.Page < 1 || f.Page > ctx.PageCount {
+			return fmt.Errorf("invalid page number %d of field '%s'", f.Page, f.Name)
+		}
+
+		d, err := addField(ctx, form, f)
+		if err != nil {
+			return fmt.Errorf("failed to add field '%s': %w", f.Name, err)
+		}
+		nodes[f.Name] = &fieldNode{d: d}
+	}
+	return nil
+}
+
+// ensureAcroForm returns the AcroForm dictionary of the document,
+// which is created if missing.
+func ensureAcroForm(ctx *model.Context) (types.Dict, error) {
+	form, err := acroForm(ctx)
+	if err != nil || form != nil {
+		return form, err
+	}
+
+	root, err := ctx.Catalog()
+	if err != nil {
+		return nil, err
+	}
+	form = types.Dict{
+		"Fields": types.Array{},
+	}
+	font, err := addStandardFont(ctx, form, "Helvetica")
+	if err != nil {
+		return nil, err
+	}
+	form["DA"] = types.StringLiteral("/" + font + " 0 Tf 0 g")
+	root["AcroForm"] = form
+	return form, nil
+}
+
+// addField adds the field merged with its widget annotation to the page
+// and to the field tree. The field dictionary is returned.
+func addField(ctx *model.Context, form types.Dict, f FieldSpec) (types.Dict, error) {
+	pageDict, pageRef, _, err := ctx.PageDict(f.Page, false)
+	if err != nil {
+		return nil, err
+	}
+
+	parentName, partial := splitFieldName(f.Name)
+	parent, parentRef, err := ensureParentField(ctx, form, parentName)
+	if err != nil {
+		return nil, err
+	}
+
+	d := types.Dict{
+		"Type":    types.Name("Annot"),
+		"Subtype": types.Name("Widget"),
+		"T":       textString(partial),
+		"Rect":    types.NewNumberArray(f.Rect.LLX, f.Rect.LLY, f.Rect.URX, f.Rect.URY),
+		"P":       *pageRef,
+		"F":       types.Integer(4), // Print
+	}
+	if parentRef != nil {
+		d["Parent"] = *parentRef
+	}
+	if f.Tooltip != "" {
+		d["TU"] = textString(f.Tooltip)
+	}
+
+	var flags int
+	if f.Required {
+		flags |= flagRequired
+	}
+
+	switch f.Type {
+	case fieldTypeText, fieldTypeChoice:
+		font, err := addStandardFont(ctx, form, "Helvetica")
+		if err != nil {
+			return nil, err
+		}
+		d["DA"] = types.StringLiteral("/" + font + " " + strconv.FormatFloat(f.FontSize, 'f', -1, 64) + " Tf 0 g")
+
+		if f.Type == fieldTypeText {
+			d["FT"] = types.Name("Tx")
+			if f.Multiline {
+				flags |= flagMultiline
+			}
+		} else {
+			d["FT"] = types.Name("Ch")
+			flags |= flagCombo
+			opts := make(types.Array, len(f.Options))
+			for i, o := range f.Options {
+				opts[i] = textString(o)
+			}
+			d["Opt"] = opts
+		}
+
+	case fieldTypeButton:
+		d["FT"] = types.Name("Btn")
+		d["V"] = types.Name(buttonOffState)
+		d["AS"] = types.Name(buttonOffState)
+		d["MK"] = types.Dict{"CA": types.StringLiteral("4")}
+		err = setCheckboxAppearance(ctx, form, d, f.Rect)
+		if err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("invalid field type: '%s'", f.Type)
+	}
+	if flags != 0 {
+		d["Ff"] = types.Integer(flags)
+	}
+
+	ref, err := ctx.IndRefForNewObject(d)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add the widget annotation to the page.
+	annots, err := ctx.DereferenceArray(pageDict["Annots"])
+	if err != nil {
+		return nil, err
+	}
+	pageDict["Annots"] = append(annots, *ref)
+
+	// Add the field to the field tree.
+	owner, key := form, "Fields"
+	if parent != nil {
+		owner, key = parent, "Kids"
+	}
+	kids, err := ctx.DereferenceArray(owner[key])
+	if err != nil {
+		return nil, err
+	}
+	owner[key] = append(kids, *ref)
+	return d, nil
+}
+
+// setCheckboxAppearance sets the checked and unchecked appearances of the
+// checkbox, which shows a ZapfDingbats check mark if checked.
+func setCheckboxAppearance(ctx *model.Context, form types.Dict, d types.Dict, rect Rect) error {
+	font, err := addStandardFont(ctx, form, "ZapfDingbats")
+	if err != nil {
+		return err
+	}
+	d["DA"] = types.StringLiteral("/" + font + " 0 Tf 0 g")
+
+	fontRef, err := formFontRef(ctx, form, font)
+	if err != nil {
+		return err
+	}
+
+	// The check mark is 0.76 em wide and about 0.7 em high.
+	w, h := rect.Width(), rect.Height()
+	size := min(w, h) * 0.8
+	content := fmt.Sprintf("q\n0 g\nBT\n/%s %.2f Tf\n%.2f %.2f Td\n(4) Tj\nET\nQ\n",
+		font, size, (w-0.76*size)/2, (h-0.7*size)/2)
+
+	on, err := newFormXObject(ctx, w, h, content, types.Dict{"Font": types.Dict{font: fontRef}})
+	if err != nil {
+		return err
+	}
+	off, err := newFormXObject(ctx, w, h, "", nil)
+	if err != nil {
+		return err
+	}
+	d["AP"] = types.Dict{"N": types.Dict{checkboxOnState: on, buttonOffState: off}}
+	return nil
+}
+
+// formFontRef returns the font of the form's default resources
+// with the resource name.
+func formFontRef(ctx *model.Context, form types.Dict, name string) (types.Object, error) {
+	dr, err := ctx.DereferenceDict(form["DR"])
+	if err != nil {
+		return nil, err
+	}
+	fonts, err := ctx.DereferenceDict(dr["Font"])
+	if err != nil {
+		return nil, err
+	}
+	return fonts[name], nil
+}
@@ -0,0 +1,330 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// Annotation flags hiding an annotation.
+const (
+	annotHidden = 1 << 1
+	annotNoView = 1 << 5
+)
+
+// FlattenAnnotations burns the appearances of the annotations other than
+// form fields and links, e.g. stamps, ink drawings and free text comments,
+// into the page contents of the input PDF file and writes the result to
+// the destination file. Unlike Flatten, the form stays interactive, e.g.
+// for review-and-continue workflows. Hidden annotations and annotations
+// without appearance are kept. An existing destination file is replaced.
+// No external utility is required.
+func FlattenAnnotations(inputPDFFile, destPDFFile string) error {
+	return FlattenAnnotationsContext(context.Background(), inputPDFFile, destPDFFile)
+}
+
+// FlattenAnnotationsContext is like FlattenAnnotations, but checks the
+// context for cancellation before processing.
+func FlattenAnnotationsContext(ctx context.Context, inputPDFFile, destPDFFile string) (err error) {
+	// Get the absolute paths.
+	destPDFFile, err = filepath.Abs(destPDFFile)
+	if err != nil {
+		return fmt.Errorf("failed to create the absolute path: %w", err)
+	}
+	inputs, err := absExistingFiles([]string{inputPDFFile})
+	if err != nil {
+		return err
+	}
+
+	// Create a temporary directory and remove it on defer again.
+	tmpDir, err := createTempDir("")
+	if err != nil {
+		return err
+	}
+	defer removeTempDir(tmpDir)
+
+	outputFile := filepath.Join(tmpDir, "output.pdf")
+	err = flattenAnnotationsPass(ctx, tmpDir, inputs[0], outputFile)
+	if err != nil {
+		return err
+	}
+
+	return writeDestFile(outputFile, destPDFFile, true)
+}
+
+func flattenAnnotationsPass(ctx context.Context, tmpDir, inputFile, outputFile string) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	err = flattenAnnotations(inputFile, outputFile)
+	if err != nil {
+		return fmt.Errorf("failed to flatten annotations: %w", err)
+	}
+	return nil
+}
+
+func flattenAnnotations(inputFile, outputFile string) error {
+	ctx, err := readPdfcpuContext(inputFile, "")
+	if err != nil {
+		return err
+	}
+
+	for page := 1; page <= ctx.PageCount; page++ {
+		err = flattenPageAnnotations(ctx, page)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writePdfcpuContext(ctx, outputFile)
+}
+
+// flattenPageAnnotations paints the appearances of the annotations
+// after the page content and removes the annotations with their popups.
+func flattenPageAnnotations(ctx *model.Context, page int) error {
+	d, _, inh, err := ctx.PageDict(page, false)
+	if err != nil {
+		return err
+	}
+	annots, err := ctx.DereferenceArray(d["Annots"])
+	if err != nil || len(annots) == 0 {
+		return err
+	}
+
+	var (
+		content   strings.Builder
+		xobjects  types.Dict
+		flattened = make(map[int]bool)
+		kept      = types.Array{}
+	)
+	for _, o := range annots {
+		a, err := ctx.DereferenceDict(o)
+		if err != nil {
+			return err
+		}
+		ap, ok, err := flattenableAppearance(ctx, a)
+		if err != nil {
+			return err
+		} else if !ok {
+			kept = append(kept, o)
+			continue
+		}
+		matrix, ok, err := appearanceMatrix(ctx, a, ap)
+		if err != nil {
+			return err
+		} else if !ok {
+			kept = append(kept, o)
+			continue
+		}
+
+		if xobjects == nil {
+			xobjects, err = pageXObjects(ctx, d, inh)
+			if err != nil {
+				return err
+			}
+		}
+		name := uniqueResourceName(xobjects, "FlatAnnot")
+		xobjects[name] = ap
+		fmt.Fprintf(&content, "q %s cm /%s Do Q\n", matrix, name)
+
+		if ref, ok := o.(types.IndirectRef); ok {
+			flattened[ref.ObjectNumber.Value()] = true
+		}
+	}
+	if content.Len() == 0 {
+		return nil
+	}
+
+	// Popups of the flattened annotations are removed, too.
+	annots, kept = kept, types.Array{}
+	for _, o := range annots {
+		a, err := ctx.DereferenceDict(o)
+		if err != nil {
+			return err
+		}
+		if parent, ok := a["Parent"].(types.IndirectRef); ok && flattened[parent.ObjectNumber.Value()] {
+			continue
+		}
+		kept = append(kept, o)
+	}
+	if len(kept) > 0 {
+		d["Annots"] = kept
+	} else {
+		d.Delete("Annots")
+	}
+
+	return wrapPageContent(ctx, d, content.String())
+}
+
+// flattenableAppearance returns the normal appearance stream of a visible
+// annotation other than widgets, links and popups.
+func flattenableAppearance(ctx *model.Context, a types.Dict) (types.IndirectRef, bool, error) {
+	if st := a.NameEntry("Subtype"); st == nil || *st == "Widget" || *st == "Link" || *st == "Popup" {
+		return types.IndirectRef{}, false, nil
+	}
+	return annotationAppearance(ctx, a)
+}
+
+// annotationAppearance returns the normal appearance stream of a visible
+// annotation. Appearances with states are selected by the appearance state.
+func annotationAppearance(ctx *model.Context, a types.Dict) (types.IndirectRef, bool, error) {
+	flags, err := intEntry(ctx, a, "F")
+	if err != nil || flags&(annotHidden|annotNoView) != 0 {
+		return types.IndirectRef{}, false, err
+	}
+
+	ap, err := ctx.DereferenceDict(a["AP"])
+	if err != nil || ap == nil {
+		return types.IndirectRef{}, false, err
+	}
+	n := ap["N"]
+
+	if states, err := ctx.DereferenceDict(n); err == nil && states != nil {
+		as := a.NameEntry("AS")
+		if as == nil {
+			return types.IndirectRef{}, false, nil
+		}
+		n = states[*as]
+	}
+	ref, ok := n.(types.IndirectRef)
+	return ref, ok, nil
+}
+
+// appearanceMatrix returns the transformation matrix mapping the
+// appearance's bounding box onto the annotation rectangle.
+func appearanceMatrix(ctx *model.Context, a types.Dict, ap types.IndirectRef) (string, bool, error) {
+	sd, _, err := ctx.DereferenceStreamDict(ap)
+	if err != nil || sd == nil {
+		return "", false, err
+	}
+	bboxArr, err := ctx.DereferenceArray(sd.Dict["BBox"])
+	if err != nil || len(bboxArr) != 4 {
+		return "", false, err
+	}
+	bbox, err := ctx.RectForArray(bboxArr)
+	if err != nil {
+		return "", false, err
+	}
+	rectArr, err := ctx.DereferenceArray(a["Rect"])
+	if err != nil || len(rectArr) != 4 {
+		return "", false, err
+	}
+	rect, err := ctx.RectForArray(rectArr)
+	if err != nil {
+		return "", false, err
+	}
+
+	// The bounding box is transformed by the matrix of the form first.
+	m := [6]float64{1, 0, 0, 1, 0, 0}
+	if arr, err := ctx.DereferenceArray(sd.Dict["Matrix"]); err == nil && len(arr) == 6 {
+		for i, o := range arr {
+			f, err := ctx.DereferenceNumber(o)
+			if err != nil {
+				return "", false, err
+			}
+			m[i] = f
+		}
+	}
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range [][2]float64{{bbox.LL.X, bbox.LL.Y}, {bbox.UR.X, bbox.LL.Y}, {bbox.LL.X, bbox.UR.Y}, {bbox.UR.X, bbox.UR.Y}} {
+		x := m[0]*p[0] + m[2]*p[1] + m[4]
+		y := m[1]*p[0] + m[3]*p[1] + m[5]
+		minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+		minY, maxY = math.Min(minY, y), math.Max(maxY, y)
+	}
+	if maxX-minX <= 0 || maxY-minY <= 0 {
+		return "", false, nil
+	}
+
+	sx := rect.Width() / (maxX - minX)
+	sy := rect.Height() / (maxY - minY)
+	return fmt.Sprintf("%.4f 0 0 %.4f %.4f %.4f", sx, sy, rect.LL.X-minX*sx, rect.LL.Y-minY*sy), true, nil
+}
+
+// pageXObjects returns the XObject resources of the page. Inherited
+// resources are copied into the page before.
+func pageXObjects(ctx *model.Context, d types.Dict, inh *model.InheritedPageAttrs) (types.Dict, error) {
+	res, err := ctx.DereferenceDict(d["Resources"])
+	if err != nil {
+		return nil, err
+	} else if res == nil {
+		res = types.Dict{}
+		if inh != nil && inh.Resources != nil {
+			res = inh.Resources.Clone().(types.Dict)
+		}
+		d["Resources"] = res
+	}
+
+	xobjects, err := ctx.DereferenceDict(res["XObject"])
+	if err != nil {
+		return nil, err
+	} else if xobjects == nil {
+		xobjects = types.Dict{}
+		res["XObject"] = xobjects
+	}
+	return xobjects, nil
+}
+
+// uniqueResourceName returns the prefix followed by the first
+// number not used as name within the resources.
+func uniqueResourceName(resources types.Dict, prefix string) string {
+	for i := 0; ; i++ {
+		name := fmt.Sprintf("%s%d", prefix, i)
+		if _, ok := resources[name]; !ok {
+			return name
+		}
+	}
+}
+
+// wrapPageContent paints the content after the page content, which is
+// enclosed in a saved graphics state, so its changes do not leak.
+func wrapPageContent(ctx *model.Context, d types.Dict, content string) error {
+	pre, err := ctx.StreamDictIndRef([]byte("q\n"))
+	if err != nil {
+		return err
+	}
+	post, err := ctx.StreamDictIndRef([]byte("Q\n" + content))
+	if err != nil {
+		return err
+	}
+
+	contents := types.Array{*pre}
+	o, err := ctx.Dereference(d["Contents"])
+	if err != nil {
+		return err
+	}
+	switch c := o.(type) {
+	case types.Array:
+		contents = append(contents, c...)
+	case types.StreamDict:
+		contents = append(contents, d["Contents"])
+	}
+	d["Contents"] = append(contents, *post)
+	return nil
+}
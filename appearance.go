@@ -0,0 +1,251 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"fmt"
+	"image/color"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// standardFonts map the standard 14 fonts usable for appearances
+// to their common AcroForm resource names.
+var standardFonts = map[string]string{
+	"Helvetica":             "Helv",
+	"Helvetica-Bold":        "HeBo",
+	"Helvetica-Oblique":     "HeOb",
+	"Helvetica-BoldOblique": "HeBO",
+	"Courier":               "Cour",
+	"Courier-Bold":          "CoBo",
+	"Courier-Oblique":       "CoOb",
+	"Courier-BoldOblique":   "CoBO",
+	"Times-Roman":           "TiRo",
+	"Times-Bold":            "TiBo",
+	"Times-Italic":          "TiIt",
+	"Times-BoldItalic":      "TiBI",
+	"Symbol":                "Symb",
+	"ZapfDingbats":          "ZaDb",
+}
+
+// AppearanceOptions override the default appearance of a field.
+// The backend regenerates the appearance of filled fields with it.
+// Unset values keep the default appearance of the template.
+type AppearanceOptions struct {
+	// Font is the name of one of the standard 14 fonts,
+	// e.g. "Helvetica", "Courier-Bold" or "Times-Roman".
+	Font string
+	// FontSize is the font size in points.
+	FontSize float64
+	// Color is the text color.
+	Color color.Color
+}
+
+// defaultAppearance is a parsed default appearance string, e.g. "/Helv 12 Tf 0 g".
+type defaultAppearance struct {
+	font  string
+	size  string
+	color []string
+}
+
+// parseDefaultAppearance parses the font and the color operators
+// of the default appearance string. Other operators are dropped.
+func parseDefaultAppearance(da string) defaultAppearance {
+	var (
+		a        defaultAppearance
+		operands []string
+	)
+	for _, token := range strings.Fields(da) {
+		switch token {
+		case "Tf":
+			if len(operands) >= 2 {
+				a.font = strings.TrimPrefix(operands[len(operands)-2], "/")
+				a.size = operands[len(operands)-1]
+			}
+		case "g", "rg", "k":
+			a.color = append(append([]string{}, operands...), token)
+		default:
+			operands = append(operands, token)
+			continue
+		}
+		operands = nil
+	}
+	return a
+}
+
+// String returns the default appearance string.
+func (a defaultAppearance) String() string {
+	var parts []string
+	if a.font != "" {
+		size := a.size
+		if size == "" {
+			size = "0"
+		}
+		parts = append(parts, "/"+a.font, size, "Tf")
+	}
+	if len(a.color) > 0 {
+		parts = append(parts, a.color...)
+	} else {
+		parts = append(parts, "0", "g")
+	}
+	return strings.Join(parts, " ")
+}
+
+// apply overrides the set options.
+func (o AppearanceOptions) apply(a defaultAppearance, font string) defaultAppearance {
+	if font != "" {
+		a.font = font
+	}
+	if o.FontSize > 0 {
+		a.size = strconv.FormatFloat(o.FontSize, 'f', -1, 64)
+	}
+	if o.Color != nil {
+		r, g, b, _ := o.Color.RGBA()
+		a.color = []string{colorComponent(r), colorComponent(g), colorComponent(b), "rg"}
+	}
+	return a
+}
+
+func colorComponent(c uint32) string {
+	return strconv.FormatFloat(float64(c)/0xffff, 'f', 3, 64)
+}
+
+// setAppearances writes the form PDF with the default appearances of the
+// fields overridden to the output file. The existing appearance streams
+// are removed, so they are regenerated by the backend or viewer.
+func setAppearances(inputFile, outputFile, password string, appearances map[string]AppearanceOptions) error {
+	ctx, err := readPdfcpuContext(inputFile, password)
+	if err != nil {
+		return err
+	}
+
+	form, err := acroForm(ctx)
+	if err != nil {
+		return err
+	} else if form == nil {
+		return fmt.Errorf("document has no form")
+	}
+	formDA := form.StringEntry("DA")
+
+	err = walkFields(ctx, func(name string, d types.Dict) error {
+		o, ok := appearances[name]
+		if !ok {
+			return nil
+		}
+
+		var font string
+		if o.Font != "" {
+			f, err := addStandardFont(ctx, form, o.Font)
+			if err != nil {
+				return err
+			}
+			font = f
+		}
+
+		da := formDA
+		if s := d.StringEntry("DA"); s != nil {
+			da = s
+		}
+		var current string
+		if da != nil {
+			current = *da
+		}
+		d["DA"] = types.StringLiteral(o.apply(parseDefaultAppearance(current), font).String())
+		d.Delete("AP")
+
+		// Widgets of fields with multiple widgets may have their own appearance.
+		kids, err := ctx.DereferenceArray(d["Kids"])
+		if err != nil {
+			return err
+		}
+		for _, k := range kids {
+			kd, err := ctx.DereferenceDict(k)
+			if err != nil {
+				return err
+			} else if kd == nil || kd["T"] != nil {
+				continue
+			}
+			kd.Delete("DA")
+			kd.Delete("AP")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return writePdfcpuContext(ctx, outputFile)
+}
+
+// addStandardFont adds the standard font to the default resources of the
+// form if missing and returns its resource name.
+func addStandardFont(ctx *model.Context, form types.Dict, baseFont string) (string, error) {
+	name, ok := standardFonts[baseFont]
+	if !ok {
+		return "", fmt.Errorf("invalid appearance font: '%s'", baseFont)
+	}
+
+	dr, err := ctx.DereferenceDict(form["DR"])
+	if err != nil {
+		return "", err
+	} else if dr == nil {
+		dr = types.Dict{}
+		form["DR"] = dr
+	}
+	fonts, err := ctx.DereferenceDict(dr["Font"])
+	if err != nil {
+		return "", err
+	} else if fonts == nil {
+		fonts = types.Dict{}
+		dr["Font"] = fonts
+	}
+	if _, ok := fonts[name]; ok {
+		return name, nil
+	}
+
+	fd := types.Dict{
+		"Type":     types.Name("Font"),
+		"Subtype":  types.Name("Type1"),
+		"BaseFont": types.Name(baseFont),
+	}
+	if baseFont != "Symbol" && baseFont != "ZapfDingbats" {
+		fd["Encoding"] = types.Name("WinAnsiEncoding")
+	}
+	ref, err := ctx.IndRefForNewObject(fd)
+	if err != nil {
+		return "", err
+	}
+	fonts[name] = *ref
+	return name, nil
+}
+
+// appearanceFormFile applies the appearance overrides to a copy of the
+// form PDF file within tmpDir and returns its path.
+func appearanceFormFile(tmpDir, formPDFFile, password string, appearances map[string]AppearanceOptions) (string, error) {
+	path := filepath.Join(tmpDir, "form-appearance.pdf")
+	err := setAppearances(formPDFFile, path, password, appearances)
+	if err != nil {
+		return "", fmt.Errorf("failed to set field appearances: %w", err)
+	}
+	return path, nil
+}
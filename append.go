@@ -0,0 +1,58 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+)
+
+// appendPDFsPass returns a pass appending the pages of the PDF files
+// after the pages of the input file with pdftk's cat operation.
+func appendPDFsPass(files []string) pass {
+	return func(ctx context.Context, tmpDir, inputFile, outputFile string) error {
+		err := checkPdftk()
+		if err != nil {
+			return err
+		}
+
+		inputs := []string{inputFile}
+		for _, f := range files {
+			f, err = filepath.Abs(f)
+			if err != nil {
+				return fmt.Errorf("failed to create the absolute path: %w", err)
+			}
+
+			e, err := exists(f)
+			if err != nil {
+				return fmt.Errorf("failed to check if PDF file to append exists: %w", err)
+			} else if !e {
+				return fmt.Errorf("PDF file to append does not exist: '%s'", f)
+			}
+			inputs = append(inputs, f)
+		}
+
+		err = pdftkCat(ctx, tmpDir, inputs, outputFile)
+		if err != nil {
+			return fmt.Errorf("failed to append PDF files: %w", err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,59 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+)
+
+// attachFilesPass returns a pass embedding the files as document
+// attachments with pdftk's attach_files operation.
+func attachFilesPass(files []string) pass {
+	return func(ctx context.Context, tmpDir, inputFile, outputFile string) error {
+		err := checkPdftk()
+		if err != nil {
+			return err
+		}
+
+		args := []string{inputFile, "attach_files"}
+		for _, f := range files {
+			f, err = filepath.Abs(f)
+			if err != nil {
+				return fmt.Errorf("failed to create the absolute path: %w", err)
+			}
+
+			e, err := exists(f)
+			if err != nil {
+				return fmt.Errorf("failed to check if attachment exists: %w", err)
+			} else if !e {
+				return fmt.Errorf("attachment does not exists: '%s'", f)
+			}
+			args = append(args, f)
+		}
+		args = append(args, "output", outputFile)
+
+		err = runPdftk(ctx, tmpDir, args...)
+		if err != nil {
+			return fmt.Errorf("failed to attach files: %w", err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,53 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import "context"
+
+var (
+	// Pdftk fills the forms with the pdftk utility.
+	// This is the default backend if pdftk is installed.
+	Pdftk Backend = pdftkBackend{}
+
+	// Pdfcpu fills the forms in pure Go with the pdfcpu library.
+	// No external binary is required. This is the default backend
+	// if pdftk is not installed. pdfcpu can not flatten forms itself,
+	// so the field appearances generated by pdfcpu are painted into
+	// the pages instead. Widgets without appearance are dropped.
+	Pdfcpu Backend = pdfcpuBackend{}
+)
+
+// Backend fills the fields of a form PDF file.
+type Backend interface {
+	// Fill fills the form PDF file with the form values and writes the
+	// filled PDF to outputFile. Intermediate files may be created within tmpDir.
+	Fill(ctx context.Context, tmpDir string, form Form, formPDFFile, outputFile string, opts Options) error
+}
+
+// backend returns the backend of the options. Without backend, the pdftk
+// backend is used if pdftk is installed and the pdfcpu backend otherwise.
+// Capabilities reports the weaker flattening of the pdfcpu fallback.
+func (o Options) backend() Backend {
+	if o.Backend != nil {
+		return o.Backend
+	} else if checkPdftk() != nil {
+		return Pdfcpu
+	}
+	return Pdftk
+}
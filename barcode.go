@@ -0,0 +1,143 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/ean"
+)
+
+// barcodeQuietZone is the width of the blank margins of linear barcodes in modules.
+const barcodeQuietZone = 10
+
+// barcodeModuleWidth is the width of a rendered linear barcode module in pixels.
+const barcodeModuleWidth = 4
+
+// BarcodeFormat is the symbology of a linear barcode.
+type BarcodeFormat string
+
+// The supported barcode formats.
+const (
+	// BarcodeCode128 encodes ASCII text.
+	BarcodeCode128 BarcodeFormat = "code128"
+	// BarcodeEAN encodes 7 or 8 digits as EAN-8 and 12 or 13 digits as EAN-13.
+	// The check digit is calculated if omitted.
+	BarcodeEAN BarcodeFormat = "ean"
+)
+
+// Barcode is a form value placing a linear barcode into the rectangle
+// of a form field, typically an image push button. The barcode is
+// generated in-process and stamped onto the page like an Image value.
+// The bars fill the height of the rectangle.
+type Barcode struct {
+	// Content is the encoded text, e.g. a shipment or registration number.
+	Content string
+	// Format is the barcode symbology. Defaults to BarcodeCode128.
+	Format BarcodeFormat
+}
+
+// String returns an empty string. Barcodes have no text value.
+func (Barcode) String() string {
+	return ""
+}
+
+// BarcodeStamp places a linear barcode at fixed page coordinates.
+type BarcodeStamp struct {
+	Barcode
+	// Page is the page number starting with 1.
+	Page int
+	// Rect is the rectangle in PDF user space units
+	// the barcode is fitted into.
+	Rect Rect
+}
+
+// encodeImage returns the barcode as PNG image with the aspect ratio of the rectangle.
+func (b Barcode) encodeImage(rect Rect) ([]byte, error) {
+	var (
+		code barcode.Barcode
+		err  error
+	)
+	switch b.Format {
+	case "", BarcodeCode128:
+		code, err = code128.Encode(b.Content)
+	case BarcodeEAN:
+		code, err = ean.Encode(b.Content)
+	default:
+		return nil, fmt.Errorf("invalid barcode format: '%s'", b.Format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode barcode: %w", err)
+	}
+
+	width := (code.Bounds().Dx() + 2*barcodeQuietZone) * barcodeModuleWidth
+	height := width
+	if rect.Width() > 0 && rect.Height() > 0 {
+		height = int(math.Max(1, math.Round(float64(width)*rect.Height()/rect.Width())))
+	}
+	return barcodePNG(code, barcodeModuleWidth, height, barcodeQuietZone, 0)
+}
+
+// barcodeStamps returns the image stamps of the barcodes placed at fixed
+// page coordinates. The viewports are the visible regions of the pages.
+func barcodeStamps(codes []BarcodeStamp, viewports []Rect) ([]imageStamp, error) {
+	stamps := make([]imageStamp, len(codes))
+	for i, c := range codes {
+		s, err := fixedStamp(c.Barcode, c.Page, c.Rect, viewports)
+		if err != nil {
+			return nil, fmt.Errorf("barcode '%s': %w", c.Content, err)
+		}
+		stamps[i] = s
+	}
+	return stamps, nil
+}
+
+// barcodePNG renders the barcode as PNG image with blank margins of
+// quietX modules left and right and quietY modules above and below.
+// Each module is w by h pixels.
+func barcodePNG(code barcode.Barcode, w, h, quietX, quietY int) ([]byte, error) {
+	b := code.Bounds()
+	img := image.NewGray(image.Rect(0, 0, (b.Dx()+2*quietX)*w, (b.Dy()+2*quietY)*h))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			if color.GrayModel.Convert(code.At(b.Min.X+x, b.Min.Y+y)).(color.Gray).Y >= 0x80 {
+				continue
+			}
+			module := image.Rect(x*w, y*h, (x+1)*w, (y+1)*h).Add(image.Pt(quietX*w, quietY*h))
+			draw.Draw(img, module, image.Black, image.Point{}, draw.Src)
+		}
+	}
+
+	var buf bytes.Buffer
+	err := png.Encode(&buf, img)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,135 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+)
+
+// BatchResult is the outcome of a single record of a batch fill.
+type BatchResult struct {
+	// Data is the filled PDF. It is nil on error.
+	Data []byte
+	// Err is the error of the record, if any.
+	Err error
+}
+
+// FillBatch fills the PDF form once per form and returns the results in
+// the order of the forms. The form PDF is validated once and the fields
+// required by the form checks and the checkbox values are read only once
+// for all records. The radio, list box and maximum length checks still
+// read the form PDF for each record. The records are filled concurrently,
+// bounded by the number of CPUs. Use a Filler to control the parallelism.
+// A failing record does not abort the others, see BatchResult.Err.
+// An error is returned if the form PDF is invalid.
+func FillBatch(formPDFFile string, forms []Form, options ...Options) ([]BatchResult, error) {
+	return FillBatchContext(context.Background(), formPDFFile, forms, options...)
+}
+
+// FillBatchContext is like FillBatch, but the context is used to cancel
+// the fill processes and the spawned external processes.
+func FillBatchContext(ctx context.Context, formPDFFile string, forms []Form, options ...Options) ([]BatchResult, error) {
+	return NewFiller(0).FillBatch(ctx, formPDFFile, forms, options...)
+}
+
+// FillBatch is like FillBatchContext, but the records are filled by
+// the workers of the filler.
+func (f *Filler) FillBatch(ctx context.Context, formPDFFile string, forms []Form, options ...Options) ([]BatchResult, error) {
+	opts := getOptions(options)
+	ctx = opts.instrument(ctx)
+	opts.Backend = opts.backend()
+
+	inputs, err := absExistingFiles([]string{formPDFFile})
+	if err != nil {
+		return nil, err
+	}
+	formPDFFile = inputs[0]
+
+	// Create a temporary directory and remove it on defer again.
+	tmpDir, err := createTempDir(opts.TempDir)
+	if err != nil {
+		return nil, err
+	}
+	defer removeTempDir(tmpDir)
+
+	// Validate the form PDF once.
+	if !opts.XFA {
+		err = checkNotXFAOnly(formPDFFile, opts.InputPassword)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Read the fields once, so neither the form checks nor the pdftk
+	// backend have to read them for each record again.
+	fields, err := f.templateFields(ctx, formPDFFile, forms, opts)
+	if err != nil {
+		return nil, err
+	}
+	if fields != nil && opts.Backend == Pdftk {
+		resolved := make([]Form, len(forms))
+		for i, form := range forms {
+			resolved[i] = resolveButtonValues(form, fields)
+		}
+		forms = resolved
+	}
+
+	// The filled records are written into the temporary directory.
+	recordOpts := opts
+	recordOpts.Overwrite = true
+
+	results := make([]BatchResult, len(forms))
+	var wg sync.WaitGroup
+	for i := range forms {
+		// Wait for a free worker before starting the record.
+		err = f.acquire(ctx)
+		if err != nil {
+			// The remaining records are not filled.
+			for j := i; j < len(forms); j++ {
+				results[j].Err = fmt.Errorf("record %d: %w", j+1, err)
+			}
+			break
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer f.release()
+
+			destFile := filepath.Join(tmpDir, fmt.Sprintf("record-%d.pdf", i))
+			err := fill(ctx, forms[i], fileSource(formPDFFile), destFile, fields, nil, recordOpts)
+			if err != nil {
+				results[i].Err = fmt.Errorf("record %d: %w", i+1, err)
+				return
+			}
+
+			results[i].Data, err = ioutil.ReadFile(destFile)
+			if err != nil {
+				results[i].Err = fmt.Errorf("record %d: failed to read filled PDF: %w", i+1, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	return results, nil
+}
@@ -0,0 +1,160 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// Position is the anchor of a stamp on the page.
+type Position string
+
+// The stamp positions.
+const (
+	PositionTopLeft      Position = "tl"
+	PositionTopCenter    Position = "tc"
+	PositionTopRight     Position = "tr"
+	PositionLeft         Position = "l"
+	PositionCenter       Position = "c"
+	PositionRight        Position = "r"
+	PositionBottomLeft   Position = "bl"
+	PositionBottomCenter Position = "bc"
+	PositionBottomRight  Position = "br"
+)
+
+// offset returns the stamp offset keeping the margin to the page edges.
+func (p Position) offset(margin float64) (dx, dy float64) {
+	switch p {
+	case PositionTopLeft, PositionLeft, PositionBottomLeft:
+		dx = margin
+	case PositionTopRight, PositionRight, PositionBottomRight:
+		dx = -margin
+	}
+	switch p {
+	case PositionBottomLeft, PositionBottomCenter, PositionBottomRight:
+		dy = margin
+	case PositionTopLeft, PositionTopCenter, PositionTopRight:
+		dy = -margin
+	}
+	return
+}
+
+// Bates stamps a sequential identifier onto every page,
+// e.g. "ACME000001", "ACME000002" and so on.
+type Bates struct {
+	// Prefix is prepended to the page number.
+	Prefix string
+	// Start is the number of the first page. Defaults to 1 if zero.
+	Start int
+	// Digits is the minimum number of digits. The number is padded
+	// with leading zeros. Defaults to 6 if zero.
+	Digits int
+	// Position is the stamp position. Defaults to the bottom right corner.
+	Position Position
+	// Margin is the distance to the page edges in points. Defaults to 20 if zero.
+	Margin float64
+	// FontSize is the font size in points. Defaults to 10 if zero.
+	FontSize int
+}
+
+func (b Bates) withDefaults() Bates {
+	if b.Start == 0 {
+		b.Start = 1
+	}
+	if b.Digits == 0 {
+		b.Digits = 6
+	}
+	if b.Position == "" {
+		b.Position = PositionBottomRight
+	}
+	if b.Margin == 0 {
+		b.Margin = 20
+	}
+	if b.FontSize == 0 {
+		b.FontSize = 10
+	}
+	return b
+}
+
+// Number returns the identifier of the page with the given index starting with 0.
+func (b Bates) Number(index int) string {
+	b = b.withDefaults()
+	return fmt.Sprintf("%s%0*d", b.Prefix, b.Digits, b.Start+index)
+}
+
+// pass stamps the identifiers onto the pages.
+func (b *Bates) pass(ctx context.Context, tmpDir, inputFile, outputFile string) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	err = stampBates(inputFile, outputFile, b.withDefaults())
+	if err != nil {
+		return fmt.Errorf("failed to stamp Bates numbers: %w", err)
+	}
+	return nil
+}
+
+func stampBates(inputFile, outputFile string, b Bates) error {
+	in, err := os.Open(inputFile)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	conf := pdfcpuReadConfig("")
+	pageCount, err := api.PageCount(in, conf)
+	if err != nil {
+		return err
+	}
+
+	dx, dy := b.Position.offset(b.Margin)
+	desc := fmt.Sprintf("fontname:Helvetica, points:%d, position:%s, offset:%.2f %.2f, scalefactor:1 abs, rotation:0, fillcolor:#000000",
+		b.FontSize, b.Position, dx, dy)
+
+	m := make(map[int][]*model.Watermark, pageCount)
+	for i := 0; i < pageCount; i++ {
+		wm, err := api.TextWatermark(b.Number(i), desc, true, false, types.POINTS)
+		if err != nil {
+			return err
+		}
+		m[i+1] = []*model.Watermark{wm}
+	}
+
+	_, err = in.Seek(0, 0)
+	if err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	err = api.AddWatermarksSliceMap(in, &out, m, conf)
+	if err != nil {
+		return err
+	}
+
+	return writeFile(outputFile, &out)
+}
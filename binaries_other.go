@@ -0,0 +1,44 @@
+//go:build !windows
+
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+// ghostscriptNames are the binary names of the Ghostscript console
+// application searched within the PATH in order.
+var ghostscriptNames = []string{"gs"}
+
+// pdftkInstallPaths returns the default install locations of pdftk
+// outside the PATH. The package managers install it within the PATH.
+func pdftkInstallPaths() []string {
+	return nil
+}
+
+// ghostscriptInstallPaths returns the default install locations of
+// Ghostscript outside the PATH. The package managers install it
+// within the PATH.
+func ghostscriptInstallPaths() []string {
+	return nil
+}
+
+// libreOfficeInstallPaths returns the default install locations of
+// LibreOffice outside the PATH, which is the application bundle on macOS.
+func libreOfficeInstallPaths() []string {
+	return []string{"/Applications/LibreOffice.app/Contents/MacOS/soffice"}
+}
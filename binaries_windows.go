@@ -0,0 +1,72 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// ghostscriptNames are the binary names of the Ghostscript console
+// application searched within the PATH in order.
+var ghostscriptNames = []string{"gswin64c", "gswin32c", "gs"}
+
+// programFilesDirs returns the program files directories of the host.
+func programFilesDirs() (dirs []string) {
+	for _, env := range []string{"ProgramFiles", "ProgramFiles(x86)", "ProgramW6432"} {
+		dir := os.Getenv(env)
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	return
+}
+
+// pdftkInstallPaths returns the default install locations of the
+// PDFtk Server installer, which does not always update the PATH.
+func pdftkInstallPaths() (paths []string) {
+	for _, dir := range programFilesDirs() {
+		paths = append(paths,
+			filepath.Join(dir, "PDFtk Server", "bin", "pdftk.exe"),
+			filepath.Join(dir, "PDFtk", "bin", "pdftk.exe"),
+		)
+	}
+	return
+}
+
+// ghostscriptInstallPaths returns the default install locations of
+// Ghostscript, which are versioned, e.g. "C:\Program Files\gs\gs10.03.1".
+func ghostscriptInstallPaths() (paths []string) {
+	for _, dir := range programFilesDirs() {
+		for _, name := range []string{"gswin64c.exe", "gswin32c.exe"} {
+			matches, _ := filepath.Glob(filepath.Join(dir, "gs", "gs*", "bin", name))
+			paths = append(paths, matches...)
+		}
+	}
+	return
+}
+
+// libreOfficeInstallPaths returns the default install locations of
+// LibreOffice, which does not update the PATH.
+func libreOfficeInstallPaths() (paths []string) {
+	for _, dir := range programFilesDirs() {
+		paths = append(paths, filepath.Join(dir, "LibreOffice", "program", "soffice.exe"))
+	}
+	return
+}
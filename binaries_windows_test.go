@@ -0,0 +1,113 @@
+//go:build windows
+
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPdftkInstallPaths(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want []string
+	}{
+		{
+			name: "no program files",
+			env:  map[string]string{"ProgramFiles": "", "ProgramFiles(x86)": "", "ProgramW6432": ""},
+		},
+		{
+			name: "program files",
+			env:  map[string]string{"ProgramFiles": `C:\Program Files`, "ProgramFiles(x86)": "", "ProgramW6432": ""},
+			want: []string{
+				`C:\Program Files\PDFtk Server\bin\pdftk.exe`,
+				`C:\Program Files\PDFtk\bin\pdftk.exe`,
+			},
+		},
+		{
+			name: "program files x86",
+			env: map[string]string{
+				"ProgramFiles":      `C:\Program Files`,
+				"ProgramFiles(x86)": `C:\Program Files (x86)`,
+				"ProgramW6432":      "",
+			},
+			want: []string{
+				`C:\Program Files\PDFtk Server\bin\pdftk.exe`,
+				`C:\Program Files\PDFtk\bin\pdftk.exe`,
+				`C:\Program Files (x86)\PDFtk Server\bin\pdftk.exe`,
+				`C:\Program Files (x86)\PDFtk\bin\pdftk.exe`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			got := pdftkInstallPaths()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPdftkBinaryInstallPath(t *testing.T) {
+	dir := t.TempDir()
+	binDir := filepath.Join(dir, "PDFtk Server", "bin")
+	err := os.MkdirAll(binDir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		// The .exe extension is resolved by the lookup.
+		{name: "set path without extension", path: filepath.Join(binDir, "pdftk"), want: filepath.Join(binDir, "pdftk.exe")},
+		{name: "install path", want: filepath.Join(binDir, "pdftk.exe")},
+	}
+	writeExecutable(t, binDir, "pdftk")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PATH", "")
+			t.Setenv(PdftkEnv, "")
+			t.Setenv("ProgramFiles", dir)
+			t.Setenv("ProgramFiles(x86)", "")
+			t.Setenv("ProgramW6432", "")
+			SetPdftkPath(tt.path)
+			defer SetPdftkPath("")
+
+			got, err := pdftkBinary()
+			if err != nil {
+				t.Fatal(err)
+			} else if got != tt.want {
+				t.Fatalf("got path %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
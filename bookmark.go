@@ -0,0 +1,152 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"path/filepath"
+)
+
+// Bookmark is an entry of the document outline.
+type Bookmark struct {
+	// Title is the displayed bookmark title.
+	Title string
+	// Page is the target page number starting with 1.
+	Page int
+	// Children are the nested bookmarks.
+	Children []Bookmark
+}
+
+// SetBookmarks replaces the bookmarks of the input PDF file with the
+// given bookmark tree and writes the result to the destination file.
+// An existing destination file is replaced. The pdftk utility is required.
+func SetBookmarks(inputPDFFile, destPDFFile string, bookmarks []Bookmark) error {
+	return SetBookmarksContext(context.Background(), inputPDFFile, destPDFFile, bookmarks)
+}
+
+// SetBookmarksContext is like SetBookmarks, but the context is used to cancel
+// the spawned external processes.
+func SetBookmarksContext(ctx context.Context, inputPDFFile, destPDFFile string, bookmarks []Bookmark) (err error) {
+	// Get the absolute paths.
+	destPDFFile, err = filepath.Abs(destPDFFile)
+	if err != nil {
+		return fmt.Errorf("failed to create the absolute path: %w", err)
+	}
+	inputs, err := absExistingFiles([]string{inputPDFFile})
+	if err != nil {
+		return err
+	}
+
+	err = checkPdftk()
+	if err != nil {
+		return err
+	}
+
+	// Create a temporary directory and remove it on defer again.
+	tmpDir, err := createTempDir("")
+	if err != nil {
+		return err
+	}
+	defer removeTempDir(tmpDir)
+
+	bookmarksFile := filepath.Join(tmpDir, "bookmarks.txt")
+	err = writeBookmarksFile(bookmarksFile, bookmarks)
+	if err != nil {
+		return fmt.Errorf("failed to write bookmarks file: %w", err)
+	}
+
+	outputFile := filepath.Join(tmpDir, "output.pdf")
+	err = runPdftk(ctx, tmpDir, inputs[0], "update_info_utf8", bookmarksFile, "output", outputFile)
+	if err != nil {
+		return fmt.Errorf("failed to set bookmarks: %w", err)
+	}
+
+	return writeDestFile(outputFile, destPDFFile, true)
+}
+
+// writeBookmarksFile writes the bookmark tree in the pdftk dump_data format.
+func writeBookmarksFile(path string, bookmarks []Bookmark) (err error) {
+	file, err := createFile(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		cerr := file.Close()
+		if err == nil {
+			err = cerr
+		}
+	}()
+
+	w := bufio.NewWriter(file)
+	err = writeBookmarks(w, bookmarks, 1)
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func writeBookmarks(w *bufio.Writer, bookmarks []Bookmark, level int) error {
+	for _, b := range bookmarks {
+		if b.Page < 1 {
+			return fmt.Errorf("invalid page number %d for bookmark '%s'", b.Page, b.Title)
+		}
+
+		fmt.Fprintf(w, "BookmarkBegin\nBookmarkTitle: %s\nBookmarkLevel: %d\nBookmarkPageNumber: %d\n",
+			singleLine(b.Title), level, b.Page)
+
+		err := writeBookmarks(w, b.Children, level+1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flatBookmark is a bookmark as listed by pdftk's dump_data.
+type flatBookmark struct {
+	Title string
+	Level int
+	Page  int
+}
+
+// bookmarkTree builds the bookmark tree from the flat dump_data list.
+// Levels start with 1. Entries skipping a level are attached to the
+// preceding bookmark.
+func bookmarkTree(flat []flatBookmark) []Bookmark {
+	bookmarks, _ := buildBookmarks(flat, 1)
+	return bookmarks
+}
+
+// buildBookmarks consumes the bookmarks of the given level and their
+// children and returns the remaining entries.
+func buildBookmarks(flat []flatBookmark, level int) (bookmarks []Bookmark, rest []flatBookmark) {
+	for len(flat) > 0 && flat[0].Level >= level {
+		if flat[0].Level > level && len(bookmarks) > 0 {
+			last := &bookmarks[len(bookmarks)-1]
+			last.Children, flat = buildBookmarks(flat, level+1)
+			continue
+		}
+
+		bookmarks = append(bookmarks, Bookmark{Title: flat[0].Title, Page: flat[0].Page})
+		flat = flat[1:]
+	}
+	return bookmarks, flat
+}
@@ -0,0 +1,221 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// labelFontSize is the font size of field labels.
+const labelFontSize = 9
+
+// FormBuilder builds a fillable form PDF from scratch, e.g. to create
+// form PDFs for tests. Labels are shown with the Helvetica font.
+//
+//	b := fillpdf.NewFormBuilder()
+//	page := b.AddPage(595, 842)
+//	b.AddTextField(page, "name", "Name", fillpdf.Rect{LLX: 50, LLY: 750, URX: 300, URY: 770})
+//	err := b.Build("form.pdf")
+type FormBuilder struct {
+	pages  []Rect
+	labels []formLabel
+	fields []FieldSpec
+}
+
+// formLabel is a text shown on a page.
+type formLabel struct {
+	page int
+	x, y float64
+	size float64
+	text string
+}
+
+// NewFormBuilder returns a new form builder without pages.
+func NewFormBuilder() *FormBuilder {
+	return &FormBuilder{}
+}
+
+// AddPage adds a page sized w by h points and returns its page number.
+// A4 is 595 by 842 points and US Letter 612 by 792 points.
+func (b *FormBuilder) AddPage(w, h float64) int {
+	b.pages = append(b.pages, Rect{URX: w, URY: h})
+	return len(b.pages)
+}
+
+// AddLabel adds the text at the position of its baseline to the page.
+// Characters missing from the WinAnsi encoding are replaced.
+func (b *FormBuilder) AddLabel(page int, x, y, size float64, text string) {
+	b.labels = append(b.labels, formLabel{page: page, x: x, y: y, size: size, text: text})
+}
+
+// AddField adds the field with the label shown above it.
+// Checkbox labels are shown to the right instead. An empty label is omitted.
+func (b *FormBuilder) AddField(f FieldSpec, label string) {
+	b.fields = append(b.fields, f)
+	if label == "" {
+		return
+	}
+
+	if f.Type == fieldTypeButton {
+		y := f.Rect.LLY + (f.Rect.Height()-0.7*labelFontSize)/2
+		b.AddLabel(f.Page, f.Rect.URX+4, y, labelFontSize, label)
+	} else {
+		b.AddLabel(f.Page, f.Rect.LLX, f.Rect.URY+3, labelFontSize, label)
+	}
+}
+
+// AddTextField adds a labeled text field to the page.
+func (b *FormBuilder) AddTextField(page int, name, label string, rect Rect) {
+	b.AddField(FieldSpec{Type: fieldTypeText, Name: name, Page: page, Rect: rect}, label)
+}
+
+// AddCheckbox adds a labeled checkbox to the page.
+func (b *FormBuilder) AddCheckbox(page int, name, label string, rect Rect) {
+	b.AddField(FieldSpec{Type: fieldTypeButton, Name: name, Page: page, Rect: rect}, label)
+}
+
+// AddChoice adds a labeled combo box with the options to the page.
+func (b *FormBuilder) AddChoice(page int, name, label string, rect Rect, options ...string) {
+	b.AddField(FieldSpec{Type: fieldTypeChoice, Name: name, Page: page, Rect: rect, Options: options}, label)
+}
+
+// Build writes the form PDF to the destination file.
+// An existing destination file is replaced.
+func (b *FormBuilder) Build(destPDFFile string) error {
+	data, err := b.Bytes()
+	if err != nil {
+		return err
+	}
+	return writeFile(destPDFFile, bytes.NewReader(data))
+}
+
+// Bytes returns the form PDF.
+func (b *FormBuilder) Bytes() ([]byte, error) {
+	if len(b.pages) == 0 {
+		return nil, fmt.Errorf("form has no pages")
+	}
+	for _, l := range b.labels {
+		if l.page < 1 || l.page > len(b.pages) {
+			return nil, fmt.Errorf("invalid page number %d of label '%s'", l.page, l.text)
+		}
+	}
+
+	ctx, err := pdfcpu.CreateContextWithXRefTable(pdfcpuReadConfig(""), &types.Dim{Width: b.pages[0].URX, Height: b.pages[0].URY})
+	if err != nil {
+		return nil, err
+	}
+
+	form, err := ensureAcroForm(ctx)
+	if err != nil {
+		return nil, err
+	}
+	font, err := addStandardFont(ctx, form, "Helvetica")
+	if err != nil {
+		return nil, err
+	}
+	fontRef, err := formFontRef(ctx, form, font)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, box := range b.pages {
+		err = b.addPage(ctx, i+1, box, font, fontRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add page %d: %w", i+1, err)
+		}
+	}
+
+	err = insertFields(ctx, b.fields)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	err = api.WriteContext(ctx, &out)
+	if err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// addPage appends the page with its labels to the page tree.
+func (b *FormBuilder) addPage(ctx *model.Context, page int, box Rect, font string, fontRef types.Object) error {
+	root, err := ctx.Catalog()
+	if err != nil {
+		return err
+	}
+	pagesRef, ok := root["Pages"].(types.IndirectRef)
+	if !ok {
+		return fmt.Errorf("invalid page tree")
+	}
+	pages, err := ctx.DereferenceDict(pagesRef)
+	if err != nil {
+		return err
+	}
+
+	enc := encoding.ReplaceUnsupported(charmap.Windows1252.NewEncoder())
+	var content strings.Builder
+	for _, l := range b.labels {
+		if l.page != page {
+			continue
+		}
+		text, err := enc.String(l.text)
+		if err != nil {
+			return err
+		}
+		escaped, err := types.Escape(text)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&content, "BT\n/%s %.2f Tf\n%.2f %.2f Td\n(%s) Tj\nET\n", font, l.size, l.x, l.y, *escaped)
+	}
+
+	contents, err := newStream(ctx, []byte(content.String()))
+	if err != nil {
+		return err
+	}
+	pageRef, err := ctx.IndRefForNewObject(types.Dict{
+		"Type":      types.Name("Page"),
+		"Parent":    pagesRef,
+		"MediaBox":  types.NewNumberArray(box.LLX, box.LLY, box.URX, box.URY),
+		"Resources": types.Dict{"Font": types.Dict{font: fontRef}},
+		"Contents":  contents,
+	})
+	if err != nil {
+		return err
+	}
+
+	kids, err := ctx.DereferenceArray(pages["Kids"])
+	if err != nil {
+		return err
+	}
+	pages["Kids"] = append(kids, *pageRef)
+	pages["Count"] = types.Integer(len(kids) + 1)
+	ctx.PageCount = len(kids) + 1
+	return nil
+}
@@ -0,0 +1,134 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Engine is an implementation of PDF operations.
+type Engine string
+
+const (
+	// EngineNone marks unavailable operations.
+	EngineNone Engine = ""
+	// EnginePdftk is the original pdftk or PDFtk Server.
+	EnginePdftk Engine = "pdftk"
+	// EnginePdftkJava is the Java port of pdftk.
+	EnginePdftkJava Engine = "pdftk-java"
+	// EnginePdfcpu is the pure Go pdfcpu library, which is always available.
+	EnginePdfcpu Engine = "pdfcpu"
+	// EngineQpdf is the qpdf utility.
+	EngineQpdf Engine = "qpdf"
+)
+
+// HostCapabilities describes the tools detected on the host and
+// the engines used for the operations with the default options.
+type HostCapabilities struct {
+	// Pdftk is the detected pdftk flavor: EnginePdftk, EnginePdftkJava
+	// or EngineNone if pdftk is not installed.
+	Pdftk Engine
+	// PdftkPath is the path of the pdftk binary, if found.
+	PdftkPath string
+	// Qpdf, Ghostscript, Pdftoppm, Pyhanko and LibreOffice report
+	// whether the respective utility is installed.
+	Qpdf        bool
+	Ghostscript bool
+	Pdftoppm    bool
+	Pyhanko     bool
+	LibreOffice bool
+
+	// Fill is the engine filling the forms if no backend is set.
+	Fill Engine
+	// Flatten is the engine flattening the forms if no backend is set.
+	// pdfcpu paints the field appearances into the pages.
+	Flatten Engine
+	// FlattenAppearancesOnly reports that the Flatten engine paints the
+	// existing field appearances only, as done by pdfcpu. Appearances are
+	// not rendered like pdftk does and widgets without appearance are
+	// dropped from flattened forms.
+	FlattenAppearancesOnly bool
+	// Encrypt is the engine applying the Encryption option.
+	Encrypt Engine
+	// EncryptAES256 is the engine applying the Encryption option
+	// with the EncryptAES256 strength.
+	EncryptAES256 Engine
+	// Decrypt is the engine removing the encryption with Decrypt.
+	Decrypt Engine
+	// Linearize is the engine applying the Linearize option.
+	Linearize Engine
+}
+
+// Capabilities detects the tools installed on the host and reports
+// which engines are used for the operations. Operations requiring pdftk
+// fall back to pdfcpu if pdftk is not installed.
+func Capabilities() HostCapabilities {
+	return CapabilitiesContext(context.Background())
+}
+
+// CapabilitiesContext is like Capabilities, but the context is used to
+// cancel the spawned version check of pdftk.
+func CapabilitiesContext(ctx context.Context) HostCapabilities {
+	var c HostCapabilities
+
+	path, err := pdftkBinary()
+	if err == nil {
+		c.PdftkPath = path
+		c.Pdftk = pdftkFlavor(ctx, path)
+	}
+	c.Qpdf = checkQpdf() == nil
+	_, err = ghostscriptBinary()
+	c.Ghostscript = err == nil
+	c.Pdftoppm = checkPdftoppm() == nil
+	_, err = exec.LookPath("pyhanko")
+	c.Pyhanko = err == nil
+	_, err = (&LibreOfficeBackend{}).binary()
+	c.LibreOffice = err == nil
+
+	// pdfcpu is the fallback of all pdftk operations.
+	c.Fill, c.Flatten, c.Encrypt, c.Decrypt = EnginePdfcpu, EnginePdfcpu, EnginePdfcpu, EnginePdfcpu
+	if c.Pdftk != EngineNone {
+		c.Fill, c.Flatten, c.Encrypt, c.Decrypt = c.Pdftk, c.Pdftk, c.Pdftk, c.Pdftk
+	}
+	c.FlattenAppearancesOnly = c.Flatten == EnginePdfcpu
+	c.EncryptAES256 = EnginePdfcpu
+	if c.Qpdf {
+		c.Linearize, c.EncryptAES256 = EngineQpdf, EngineQpdf
+	}
+	return c
+}
+
+// pdftkFlavor returns whether the pdftk binary is the original pdftk
+// or the Java port, which reports itself as "pdftk port to java".
+// Binaries failing the version check are reported as original pdftk.
+func pdftkFlavor(ctx context.Context, path string) Engine {
+	if strings.Contains(strings.ToLower(filepath.Base(path)), "java") {
+		return EnginePdftkJava
+	}
+
+	out, err := runCommandOutputInPath(ctx, "", path, "--version")
+	if err == nil && bytes.Contains(bytes.ToLower(out), []byte("java")) {
+		return EnginePdftkJava
+	}
+	return EnginePdftk
+}
@@ -0,0 +1,97 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import "strings"
+
+// checkboxStrings map the textual checkbox values to their state.
+var checkboxStrings = map[string]bool{
+	"yes":   true,
+	"y":     true,
+	"on":    true,
+	"true":  true,
+	"1":     true,
+	"x":     true,
+	"no":    false,
+	"n":     false,
+	"off":   false,
+	"false": false,
+	"0":     false,
+}
+
+// mapCheckboxes returns the form with the checkbox values translated
+// to the export values of the CheckboxOn and CheckboxStates options.
+// The form is only copied if a value is translated.
+func (o Options) mapCheckboxes(form Form) Form {
+	if o.CheckboxOn == "" && len(o.CheckboxStates) == 0 {
+		return form
+	}
+
+	var mapped Form
+	for key, value := range form {
+		s, ok := o.checkboxValue(key, value)
+		if !ok {
+			continue
+		}
+
+		if mapped == nil {
+			mapped = make(Form, len(form))
+			for k, v := range form {
+				mapped[k] = v
+			}
+		}
+		mapped[key] = s
+	}
+
+	if mapped == nil {
+		return form
+	}
+	return mapped
+}
+
+// checkboxValue returns the export value of the checkbox value
+// if it is translated.
+func (o Options) checkboxValue(key string, value interface{}) (string, bool) {
+	on, field := o.CheckboxStates[key]
+	if !field {
+		on = o.CheckboxOn
+	}
+	if on == "" {
+		return "", false
+	}
+
+	var checked bool
+	switch v := value.(type) {
+	case bool:
+		checked = v
+	case string:
+		c, ok := checkboxStrings[strings.ToLower(strings.TrimSpace(v))]
+		if !field || !ok {
+			return "", false
+		}
+		checked = c
+	default:
+		return "", false
+	}
+
+	if !checked {
+		return buttonOffState, true
+	}
+	return on, true
+}
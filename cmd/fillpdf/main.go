@@ -0,0 +1,251 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+// Command fillpdf fills PDF forms from the command line.
+//
+// Usage:
+//
+//	fillpdf fill [flags] -data values.json -o filled.pdf form.pdf
+//	fillpdf fields [-json] form.pdf
+//	fillpdf flatten [flags] input.pdf output.pdf
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/desertbit/fillpdf"
+	"gopkg.in/yaml.v3"
+)
+
+const usage = `Usage: fillpdf <command> [flags] [arguments]
+
+Commands:
+  fill      fill a PDF form with JSON or YAML values
+  fields    list the form fields of a PDF
+  flatten   flatten the form fields of a PDF
+
+Run "fillpdf <command> -h" for the command flags.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "fill":
+		err = runFill(os.Args[2:])
+	case "fields":
+		err = runFields(os.Args[2:])
+	case "flatten":
+		err = runFlatten(os.Args[2:])
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(os.Stdout, usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "fillpdf: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// commonFlags are the flags shared by the fill commands.
+type commonFlags struct {
+	backend  string
+	password string
+}
+
+func (c *commonFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.backend, "backend", "", "fill backend: pdftk, pdfcpu or libreoffice (default pdftk if installed, pdfcpu otherwise)")
+	fs.StringVar(&c.password, "password", "", "password of a password protected input PDF")
+}
+
+func (c *commonFlags) options() (fillpdf.Options, error) {
+	opts := fillpdf.Options{
+		Overwrite:     true,
+		InputPassword: c.password,
+	}
+	switch c.backend {
+	case "":
+		// Use pdftk if installed and fall back to pdfcpu.
+	case "pdftk":
+		opts.Backend = fillpdf.Pdftk
+	case "pdfcpu":
+		opts.Backend = fillpdf.Pdfcpu
+	case "libreoffice":
+		opts.Backend = &fillpdf.LibreOfficeBackend{}
+	default:
+		return opts, fmt.Errorf("invalid backend: %s", c.backend)
+	}
+	return opts, nil
+}
+
+func runFill(args []string) error {
+	fs := flag.NewFlagSet("fill", flag.ExitOnError)
+	var (
+		common  commonFlags
+		data    = fs.String("data", "-", "JSON or YAML file with the form values, - reads stdin")
+		output  = fs.String("o", "", "output PDF file (required)")
+		flatten = fs.Bool("flatten", true, "flatten the filled form")
+		strict  = fs.Bool("strict", false, "fail on form values without matching field")
+	)
+	common.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: fillpdf fill [flags] -o filled.pdf form.pdf")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 || *output == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	form, err := readForm(*data)
+	if err != nil {
+		return err
+	}
+
+	opts, err := common.options()
+	if err != nil {
+		return err
+	}
+	opts.Flatten = *flatten
+	opts.Strict = *strict
+
+	return fillpdf.Fill(form, fs.Arg(0), *output, opts)
+}
+
+func runFields(args []string) error {
+	fs := flag.NewFlagSet("fields", flag.ExitOnError)
+	var (
+		asJSON   = fs.Bool("json", false, "print the fields as JSON")
+		password = fs.String("password", "", "password of a password protected PDF")
+	)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: fillpdf fields [flags] form.pdf")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	fields, err := fillpdf.GetFields(fs.Arg(0), fillpdf.Options{InputPassword: *password})
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		e := json.NewEncoder(os.Stdout)
+		e.SetIndent("", "  ")
+		return e.Encode(fields)
+	}
+
+	for _, f := range fields {
+		line := f.Type + "\t" + f.Name
+		if f.Value != "" {
+			line += "\t" + f.Value
+		}
+		if len(f.Options) > 0 {
+			line += "\t[" + strings.Join(f.Options, ", ") + "]"
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
+
+func runFlatten(args []string) error {
+	fs := flag.NewFlagSet("flatten", flag.ExitOnError)
+	var common commonFlags
+	common.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: fillpdf flatten [flags] input.pdf output.pdf")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	opts, err := common.options()
+	if err != nil {
+		return err
+	}
+	opts.Flatten = true
+
+	// Filling no values flattens the current field values.
+	return fillpdf.Fill(fillpdf.Form{}, fs.Arg(0), fs.Arg(1), opts)
+}
+
+// readForm reads the form values from the JSON or YAML file.
+// YAML is detected by the .yaml and .yml file extensions.
+func readForm(path string) (fillpdf.Form, error) {
+	var (
+		r   io.Reader
+		err error
+	)
+	if path == "-" {
+		r = os.Stdin
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext != ".yaml" && ext != ".yml" {
+		return fillpdf.FormFromJSON(r)
+	}
+
+	// Convert YAML to JSON to share the JSON value conversion.
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var v map[string]interface{}
+	err = yaml.Unmarshal(data, &v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode YAML form values: %w", err)
+	}
+	j, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert YAML form values: %w", err)
+	}
+	return fillpdf.FormFromJSON(bytes.NewReader(j))
+}
@@ -0,0 +1,302 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/font"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// customComboValues returns the values of editable combo boxes within the
+// form PDF file which are not part of their option lists. The backends
+// drop or do not show such custom values, so they are set afterwards.
+func customComboValues(form Form, pdfFile, password string) (map[string]string, error) {
+	ctx, err := readPdfcpuContext(pdfFile, password)
+	if err != nil {
+		return nil, err
+	}
+	return customComboValuesContext(ctx, form)
+}
+
+// customComboValuesContext is like customComboValues, but reads the
+// combo boxes of the pdfcpu context.
+func customComboValuesContext(ctx *model.Context, form Form) (map[string]string, error) {
+	values := make(map[string]string)
+	err := walkFields(ctx, func(name string, d types.Dict) error {
+		value, ok := form[name]
+		if !ok {
+			return nil
+		}
+		switch value.(type) {
+		case bool, []string, nil:
+			return nil
+		}
+
+		_, typ, err := widgetField(ctx, d)
+		if err != nil || typ != "Ch" {
+			return err
+		}
+		flags, err := inheritedFlags(ctx, d)
+		if err != nil {
+			return err
+		} else if flags&flagCombo == 0 || flags&flagEdit == 0 {
+			return nil
+		}
+
+		s := formatValue(value)
+		opts, err := choiceOptions(ctx, d)
+		if err != nil {
+			return err
+		}
+		for _, o := range opts {
+			if o == s {
+				return nil
+			}
+		}
+		values[name] = s
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// choiceOptions returns the export values of the options of the choice field.
+func choiceOptions(ctx *model.Context, d types.Dict) ([]string, error) {
+	arr, err := ctx.DereferenceArray(d["Opt"])
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []string
+	for _, o := range arr {
+		o, err = ctx.Dereference(o)
+		if err != nil {
+			return nil, err
+		}
+		// Options with a display text are pairs of export value and text.
+		if pair, ok := o.(types.Array); ok && len(pair) > 0 {
+			o, err = ctx.Dereference(pair[0])
+			if err != nil {
+				return nil, err
+			}
+		}
+		s, err := model.Text(o)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, s)
+	}
+	return opts, nil
+}
+
+// comboValuesPass returns a pass setting the custom values of the
+// editable combo boxes and regenerating their appearances.
+func comboValuesPass(values map[string]string) pass {
+	return func(ctx context.Context, tmpDir, inputFile, outputFile string) error {
+		err := ctx.Err()
+		if err != nil {
+			return err
+		}
+
+		err = setComboValues(inputFile, outputFile, values)
+		if err != nil {
+			return fmt.Errorf("failed to set combo box values: %w", err)
+		}
+		return nil
+	}
+}
+
+func setComboValues(inputFile, outputFile string, values map[string]string) error {
+	ctx, err := readPdfcpuContext(inputFile, "")
+	if err != nil {
+		return err
+	}
+
+	err = setComboValuesContext(ctx, values)
+	if err != nil {
+		return err
+	}
+	return writePdfcpuContext(ctx, outputFile)
+}
+
+// setComboValuesContext sets the custom values of the combo boxes of the
+// pdfcpu context and regenerates their appearances.
+func setComboValuesContext(ctx *model.Context, values map[string]string) error {
+	form, err := acroForm(ctx)
+	if err != nil {
+		return err
+	} else if form == nil {
+		return fmt.Errorf("document has no form")
+	}
+	formDA := form.StringEntry("DA")
+	formQ, err := intEntry(ctx, form, "Q")
+	if err != nil {
+		return err
+	}
+
+	return walkFields(ctx, func(name string, d types.Dict) error {
+		value, ok := values[name]
+		if !ok {
+			return nil
+		}
+		d["V"] = textString(value)
+		d.Delete("I")
+
+		da := formDA
+		if s := d.StringEntry("DA"); s != nil {
+			da = s
+		}
+		var a defaultAppearance
+		if da != nil {
+			a = parseDefaultAppearance(*da)
+		}
+		q := formQ
+		if _, ok := d.Find("Q"); ok {
+			q, err = intEntry(ctx, d, "Q")
+			if err != nil {
+				return err
+			}
+		}
+
+		// The value is shown with the simple font of the default appearance.
+		// Helvetica is used if it is missing or a composite font.
+		var ref types.Object
+		if a.font != "" {
+			ref, err = formFontRef(ctx, form, a.font)
+			if err != nil {
+				return err
+			}
+		}
+		baseFont, err := simpleFontName(ctx, ref)
+		if err != nil {
+			return err
+		} else if baseFont == "" {
+			baseFont = "Helvetica"
+			a.font, err = addStandardFont(ctx, form, baseFont)
+			if err != nil {
+				return err
+			}
+			ref, err = formFontRef(ctx, form, a.font)
+			if err != nil {
+				return err
+			}
+		}
+
+		widgets, err := fieldWidgets(ctx, d)
+		if err != nil {
+			return err
+		}
+		for _, w := range widgets {
+			rectArr, err := ctx.DereferenceArray(w["Rect"])
+			if err != nil {
+				return err
+			} else if len(rectArr) != 4 {
+				continue
+			}
+			rect, err := ctx.RectForArray(rectArr)
+			if err != nil {
+				return err
+			}
+
+			ap, err := textAppearance(ctx, rect.Width(), rect.Height(), a, q, baseFont, ref, value)
+			if err != nil {
+				return err
+			}
+			w["AP"] = types.Dict{"N": ap}
+		}
+		return nil
+	})
+}
+
+// simpleFontName returns the base font name of the simple font dictionary
+// or an empty name for missing and composite fonts. Helvetica is returned
+// for fonts other than the standard 14 fonts, whose metrics are used to
+// lay out the text.
+func simpleFontName(ctx *model.Context, ref types.Object) (string, error) {
+	d, err := ctx.DereferenceDict(ref)
+	if err != nil || d == nil {
+		return "", err
+	}
+	if subtype := d.NameEntry("Subtype"); subtype != nil && *subtype == "Type0" {
+		return "", nil
+	}
+	if name := d.NameEntry("BaseFont"); name != nil && font.IsCoreFont(*name) {
+		return *name, nil
+	}
+	return "Helvetica", nil
+}
+
+// textAppearance adds a single line appearance stream of the value shown
+// with the simple font of the form's default resources to the document.
+// Characters missing from the WinAnsi encoding are replaced.
+func textAppearance(ctx *model.Context, w, h float64, a defaultAppearance, q int, baseFont string, ref types.Object, value string) (types.IndirectRef, error) {
+	const padding = 2
+
+	enc := encoding.ReplaceUnsupported(charmap.Windows1252.NewEncoder())
+	text, err := enc.String(value)
+	if err != nil {
+		return types.IndirectRef{}, err
+	}
+	escaped, err := types.Escape(text)
+	if err != nil {
+		return types.IndirectRef{}, err
+	}
+
+	// The widths are given for a font size of 1000.
+	width := font.TextWidth(text, baseFont, 1000)
+
+	size, _ := strconv.ParseFloat(a.size, 64)
+	if size <= 0 {
+		size = min(12, (h-2*padding)*0.7)
+		if width > 0 {
+			size = min(size, (w-2*padding)*1000/width)
+		}
+	}
+
+	textWidth := width * size / 1000
+	x := float64(padding)
+	switch q {
+	case 1:
+		x = (w - textWidth) / 2
+	case 2:
+		x = w - padding - textWidth
+	}
+	ascent, descent := font.Ascent(baseFont, 1000)*size/1000, font.Descent(baseFont, 1000)*size/1000
+	y := (h-ascent-descent)/2 + descent
+
+	colorOp := "0 g"
+	if len(a.color) > 0 {
+		colorOp = strings.Join(a.color, " ")
+	}
+
+	content := fmt.Sprintf("/Tx BMC\nq\n1 1 %.2f %.2f re W n\nBT\n/%s %.2f Tf\n%s\n%.2f %.2f Td\n(%s) Tj\nET\nQ\nEMC\n",
+		w-2, h-2, a.font, size, colorOp, x, y, *escaped)
+
+	return newFormXObject(ctx, w, h, content, types.Dict{"Font": types.Dict{a.font: ref}})
+}
@@ -0,0 +1,180 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ComposePart is a form PDF filled as part of a composed document.
+type ComposePart struct {
+	// Template is the path of the form PDF file.
+	Template string
+	// Form are the values filled into the template.
+	Form Form
+}
+
+// Compose fills the templates of the parts and concatenates the filled PDFs
+// in order into the destination PDF file, e.g. for application packets.
+// The fill options are applied to each part, while the post processing
+// options, e.g. Pages, Bates, PDFA, Optimize and Linearize, are applied
+// once to the composed document, as concatenating the parts would undo
+// them. Encryption and Signature are not supported. Fields whose names are used by an earlier part
+// are renamed by prefixing their top-level field with the part number,
+// e.g. "address.city" of the second part becomes "part2.address.city".
+// The pdftk utility is required to concatenate the documents.
+func Compose(parts []ComposePart, destPDFFile string, options ...Options) error {
+	return ComposeContext(context.Background(), parts, destPDFFile, options...)
+}
+
+// ComposeContext is like Compose, but the context is used to cancel
+// the fill process and the spawned external processes.
+func ComposeContext(ctx context.Context, parts []ComposePart, destPDFFile string, options ...Options) error {
+	opts := getOptions(options)
+
+	if len(parts) == 0 {
+		return fmt.Errorf("no parts to compose")
+	} else if opts.Encryption != nil {
+		return fmt.Errorf("encryption is not supported when composing")
+	} else if opts.Signature != nil {
+		return fmt.Errorf("signing is not supported when composing")
+	}
+
+	destPDFFile, err := filepath.Abs(destPDFFile)
+	if err != nil {
+		return fmt.Errorf("failed to create the absolute path: %w", err)
+	}
+
+	err = checkPdftk()
+	if err != nil {
+		return err
+	}
+
+	// Create a temporary directory and remove it on defer again.
+	tmpDir, err := createTempDir(opts.TempDir)
+	if err != nil {
+		return err
+	}
+	defer removeTempDir(tmpDir)
+
+	// Fill all parts into the temporary directory.
+	fillOpts := opts.partOptions()
+	files := make([]string, len(parts))
+	used := make(map[string]bool)
+	for i, p := range parts {
+		file := filepath.Join(tmpDir, fmt.Sprintf("part-%d.pdf", i+1))
+		err = FillContext(ctx, p.Form, p.Template, file, fillOpts)
+		if err != nil {
+			return fmt.Errorf("part %d: %w", i+1, err)
+		}
+
+		files[i], err = renameCollidingFields(file, i+1, used)
+		if err != nil {
+			return fmt.Errorf("part %d: %w", i+1, err)
+		}
+	}
+
+	outputFile := filepath.Join(tmpDir, "output.pdf")
+	err = pdftkCat(ctx, tmpDir, files, outputFile)
+	if err != nil {
+		return err
+	}
+
+	// Apply the post processing passes to the composed document.
+	outputFile, err = runPasses(ctx, tmpDir, outputFile, opts.passes())
+	if err != nil {
+		return err
+	}
+
+	return writeDestFile(outputFile, destPDFFile, opts.Overwrite)
+}
+
+// partOptions returns the options filling a part of a composed document.
+// The options of the post processing passes are cleared, as they are
+// applied to the composed document instead.
+func (o Options) partOptions() Options {
+	o.Overwrite = true
+	o.FlattenAnnotations = false
+	o.Overlays = nil
+	o.Rotate = nil
+	o.Pages = ""
+	o.AppendPDFs = nil
+	o.Bates = nil
+	o.NUp = nil
+	o.RemoveMetadata = false
+	o.RemoveJavaScript = false
+	o.Sanitize = false
+	o.DocInfo = nil
+	o.PDFA = nil
+	o.Attachments = nil
+	o.Optimize = false
+	o.Linearize = false
+	return o
+}
+
+// renameCollidingFields renames the top-level fields of the filled part
+// containing fields with used names and returns the path of the renamed
+// PDF file. The field names of the part are added to used.
+func renameCollidingFields(pdfFile string, part int, used map[string]bool) (string, error) {
+	ctx, err := readPdfcpuContext(pdfFile, "")
+	if err != nil {
+		return "", err
+	}
+	form, err := acroForm(ctx)
+	if err != nil {
+		return "", err
+	}
+	nodes := make(map[string]*fieldNode)
+	if form != nil {
+		err = collectFieldNodes(ctx, form, nodes)
+		if err != nil {
+			return "", fmt.Errorf("failed to read form fields: %w", err)
+		}
+	}
+
+	prefix := fmt.Sprintf("part%d.", part)
+	names := make(map[string]string)
+	for name := range nodes {
+		if used[name] {
+			top, _, _ := strings.Cut(name, ".")
+			names[top] = prefix + top
+		}
+	}
+	for name := range nodes {
+		top, _, _ := strings.Cut(name, ".")
+		if _, ok := names[top]; ok {
+			name = prefix + name
+		}
+		used[name] = true
+	}
+	if len(names) == 0 {
+		return pdfFile, nil
+	}
+	used[strings.TrimSuffix(prefix, ".")] = true
+
+	renamedFile := strings.TrimSuffix(pdfFile, ".pdf") + "-renamed.pdf"
+	err = renameFields(pdfFile, renamedFile, names)
+	if err != nil {
+		return "", fmt.Errorf("failed to rename fields: %w", err)
+	}
+	return renamedFile, nil
+}
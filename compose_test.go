@@ -0,0 +1,49 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import "testing"
+
+func TestPartOptions(t *testing.T) {
+	opts := Options{
+		FlattenAnnotations: true,
+		Overlays:           []Overlay{{}},
+		Rotate:             []PageRotation{{}},
+		Pages:              "1",
+		AppendPDFs:         []string{"append.pdf"},
+		Bates:              &Bates{},
+		NUp:                &NUp{},
+		RemoveMetadata:     true,
+		RemoveJavaScript:   true,
+		Sanitize:           true,
+		DocInfo:            map[string]string{"Title": "title"},
+		PDFA:               &PDFA{},
+		Attachments:        []string{"attachment.txt"},
+		Optimize:           true,
+		Linearize:          true,
+	}
+
+	// Encryption and Signature are rejected by Compose.
+	if n := len(opts.passes()); n != 15 {
+		t.Fatalf("got %d passes of the options, want 15", n)
+	}
+	if n := len(opts.partOptions().passes()); n != 0 {
+		t.Fatalf("got %d passes of the part options, want 0", n)
+	}
+}
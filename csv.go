@@ -0,0 +1,134 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// FillCSV fills the PDF form once per CSV record read from r (mail merge).
+// The header row defines the field names of the record values.
+// One filled PDF per record is written to destDir, named after the record
+// number, e.g. 001.pdf. The paths of the filled PDF files are returned.
+func FillCSV(r io.Reader, formPDFFile, destDir string, options ...Options) ([]string, error) {
+	return FillCSVContext(context.Background(), r, formPDFFile, destDir, options...)
+}
+
+// FillCSVContext is like FillCSV, but the context is used to cancel
+// the fill process and the spawned external processes.
+func FillCSVContext(ctx context.Context, r io.Reader, formPDFFile, destDir string, options ...Options) ([]string, error) {
+	forms, err := formsFromCSV(r)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(destDir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	// Pad the record numbers to keep the files sorted.
+	format := "%0" + strconv.Itoa(len(strconv.Itoa(len(forms)))) + "d.pdf"
+
+	files := make([]string, len(forms))
+	for i, form := range forms {
+		files[i] = filepath.Join(destDir, fmt.Sprintf(format, i+1))
+		err = FillContext(ctx, form, formPDFFile, files[i], options...)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", i+1, err)
+		}
+	}
+	return files, nil
+}
+
+// FillCSVMerged is like FillCSV, but concatenates the filled PDFs of all
+// records into the single destination PDF file.
+// The pdftk utility is required to concatenate the documents.
+func FillCSVMerged(r io.Reader, formPDFFile, destPDFFile string, options ...Options) error {
+	return FillCSVMergedContext(context.Background(), r, formPDFFile, destPDFFile, options...)
+}
+
+// FillCSVMergedContext is like FillCSVMerged, but the context is used to cancel
+// the fill process and the spawned external processes.
+func FillCSVMergedContext(ctx context.Context, r io.Reader, formPDFFile, destPDFFile string, options ...Options) error {
+	opts := getOptions(options)
+
+	destPDFFile, err := filepath.Abs(destPDFFile)
+	if err != nil {
+		return fmt.Errorf("failed to create the absolute path: %w", err)
+	}
+
+	err = checkPdftk()
+	if err != nil {
+		return err
+	}
+
+	// Create a temporary directory and remove it on defer again.
+	tmpDir, err := createTempDir(opts.TempDir)
+	if err != nil {
+		return err
+	}
+	defer removeTempDir(tmpDir)
+
+	// Fill all records into the temporary directory.
+	fillOpts := opts
+	fillOpts.Overwrite = true
+	files, err := FillCSVContext(ctx, r, formPDFFile, filepath.Join(tmpDir, "records"), fillOpts)
+	if err != nil {
+		return err
+	} else if len(files) == 0 {
+		return fmt.Errorf("no CSV records to fill")
+	}
+
+	outputFile := filepath.Join(tmpDir, "output.pdf")
+	err = pdftkCat(ctx, tmpDir, files, outputFile)
+	if err != nil {
+		return err
+	}
+
+	return writeDestFile(outputFile, destPDFFile, opts.Overwrite)
+}
+
+// formsFromCSV reads the CSV records from r as form values.
+// The first record is the header with the field names.
+func formsFromCSV(r io.Reader) ([]Form, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV: %w", err)
+	} else if len(records) == 0 {
+		return nil, fmt.Errorf("CSV header row is missing")
+	}
+
+	header := records[0]
+	forms := make([]Form, len(records)-1)
+	for i, record := range records[1:] {
+		form := make(Form, len(header))
+		for j, value := range record {
+			form[header[j]] = value
+		}
+		forms[i] = form
+	}
+	return forms, nil
+}
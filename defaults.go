@@ -0,0 +1,66 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// defaultValuesPass returns a pass setting the default values of the
+// filled fields to their current values, so resetting the form restores
+// the filled values instead of clearing them.
+func defaultValuesPass(form Form) pass {
+	return func(ctx context.Context, tmpDir, inputFile, outputFile string) error {
+		err := ctx.Err()
+		if err != nil {
+			return err
+		}
+
+		err = setDefaultValues(inputFile, outputFile, form)
+		if err != nil {
+			return fmt.Errorf("failed to set default values: %w", err)
+		}
+		return nil
+	}
+}
+
+func setDefaultValues(inputFile, outputFile string, form Form) error {
+	ctx, err := readPdfcpuContext(inputFile, "")
+	if err != nil {
+		return err
+	}
+
+	err = walkFields(ctx, func(name string, d types.Dict) error {
+		if _, ok := form[name]; !ok {
+			return nil
+		}
+		if v, ok := d.Find("V"); ok {
+			d["DV"] = v
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return writePdfcpuContext(ctx, outputFile)
+}
@@ -0,0 +1,131 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// FormKind is the kind of form of a PDF.
+type FormKind string
+
+// The kinds of forms.
+const (
+	// FormNone marks documents without form.
+	FormNone FormKind = "none"
+	// FormAcroForm marks documents with an AcroForm only.
+	FormAcroForm FormKind = "acroform"
+	// FormXFA marks documents with an XFA form only. Enable the
+	// XFA option to fill them.
+	FormXFA FormKind = "xfa"
+	// FormHybrid marks documents with both an XFA form and an AcroForm.
+	FormHybrid FormKind = "hybrid"
+)
+
+// FormDetection describes the form of a PDF.
+type FormDetection struct {
+	// Kind is the kind of the form.
+	Kind FormKind
+	// Fields is the number of AcroForm fields.
+	// Parent fields of the field hierarchy are not counted.
+	Fields int
+}
+
+// HasForm returns whether the document has a form.
+func (d FormDetection) HasForm() bool {
+	return d.Kind != FormNone
+}
+
+// DetectForm returns the kind of form of the PDF file and the number of
+// its fields, e.g. to route documents without form to another pipeline.
+// The InputPassword option is used for password protected PDF files, the
+// other options are ignored. No external utility is required.
+func DetectForm(pdfFile string, options ...Options) (*FormDetection, error) {
+	return DetectFormContext(context.Background(), pdfFile, options...)
+}
+
+// DetectFormContext is like DetectForm, but checks the context
+// for cancellation before processing.
+func DetectFormContext(ctx context.Context, pdfFile string, options ...Options) (*FormDetection, error) {
+	opts := getOptions(options)
+
+	err := ctx.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	inputs, err := absExistingFiles([]string{pdfFile})
+	if err != nil {
+		return nil, err
+	}
+
+	pdfCtx, err := readPdfcpuContext(inputs[0], opts.InputPassword)
+	if err != nil {
+		return nil, err
+	}
+	return detectForm(pdfCtx)
+}
+
+func detectForm(ctx *model.Context) (*FormDetection, error) {
+	xfa, _, err := hasXFA(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var n int
+	err = walkFields(ctx, func(name string, d types.Dict) error {
+		if !hasFieldKids(ctx, d) {
+			n++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	d := &FormDetection{Kind: FormNone, Fields: n}
+	switch {
+	case xfa && n > 0:
+		d.Kind = FormHybrid
+	case xfa:
+		d.Kind = FormXFA
+	case n > 0:
+		d.Kind = FormAcroForm
+	}
+	return d, nil
+}
+
+// hasFieldKids returns whether the field has child fields.
+// Widget annotations without partial field name are no fields.
+func hasFieldKids(ctx *model.Context, d types.Dict) bool {
+	kids, err := ctx.DereferenceArray(d["Kids"])
+	if err != nil {
+		return false
+	}
+	for _, k := range kids {
+		kd, err := ctx.DereferenceDict(k)
+		if err == nil && kd != nil && kd["T"] != nil {
+			return true
+		}
+	}
+	return false
+}
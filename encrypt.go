@@ -0,0 +1,395 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// EncryptionStrength defines the encryption algorithm.
+type EncryptionStrength int
+
+const (
+	// Encrypt128Bit uses the 128 bit RC4 encryption. This is the default.
+	Encrypt128Bit EncryptionStrength = iota
+	// Encrypt40Bit uses the 40 bit RC4 encryption.
+	Encrypt40Bit
+	// EncryptAES256 uses the 256 bit AES encryption required by newer
+	// compliance policies. pdftk does not support it, so the encryption is
+	// applied with the qpdf utility or with pdfcpu if qpdf is not installed.
+	EncryptAES256
+)
+
+// Encryption defines how the filled PDF is encrypted.
+// The encryption is applied with the pdftk utility or with pdfcpu
+// if pdftk is not installed. See EncryptAES256 for the exception.
+type Encryption struct {
+	// OwnerPassword is required to change the document and its permissions.
+	// qpdf and pdfcpu use a random owner password if it is empty.
+	OwnerPassword string
+	// UserPassword is required to open the document. Optional.
+	UserPassword string
+	// Strength defines the encryption algorithm.
+	Strength EncryptionStrength
+	// Allow lists the permissions of users without the owner password.
+	// Valid values are the pdftk allow keywords: Printing, DegradedPrinting,
+	// ModifyContents, Assembly, CopyContents, ScreenReaders,
+	// ModifyAnnotations, FillIn and AllFeatures.
+	Allow []string
+	// Permissions are the permissions of users without the owner password
+	// as typed flags. They are granted in addition to Allow.
+	Permissions Permissions
+}
+
+// Permissions are the operations permitted to users without the owner
+// password. No operation is permitted by default.
+type Permissions struct {
+	// Printing permits printing in high quality.
+	Printing bool
+	// DegradedPrinting permits printing in low quality only.
+	DegradedPrinting bool
+	// ModifyContents permits changing the document contents.
+	ModifyContents bool
+	// Assembly permits inserting, rotating and deleting pages.
+	Assembly bool
+	// CopyContents permits copying text and graphics.
+	CopyContents bool
+	// ScreenReaders permits extracting text for accessibility.
+	ScreenReaders bool
+	// ModifyAnnotations permits adding annotations and filling form fields.
+	ModifyAnnotations bool
+	// FillIn permits filling form fields.
+	FillIn bool
+	// AllFeatures permits all of the above.
+	AllFeatures bool
+}
+
+// keywords returns the pdftk allow keywords of the set permissions.
+func (p Permissions) keywords() []string {
+	var keywords []string
+	for _, f := range []struct {
+		set     bool
+		keyword string
+	}{
+		{p.Printing, "Printing"},
+		{p.DegradedPrinting, "DegradedPrinting"},
+		{p.ModifyContents, "ModifyContents"},
+		{p.Assembly, "Assembly"},
+		{p.CopyContents, "CopyContents"},
+		{p.ScreenReaders, "ScreenReaders"},
+		{p.ModifyAnnotations, "ModifyAnnotations"},
+		{p.FillIn, "FillIn"},
+		{p.AllFeatures, "AllFeatures"},
+	} {
+		if f.set {
+			keywords = append(keywords, f.keyword)
+		}
+	}
+	return keywords
+}
+
+// allow returns the allow keywords of Allow and Permissions without duplicates.
+func (e *Encryption) allow() []string {
+	var allow []string
+	seen := make(map[string]bool)
+	for _, a := range append(append([]string{}, e.Allow...), e.Permissions.keywords()...) {
+		if !seen[a] {
+			seen[a] = true
+			allow = append(allow, a)
+		}
+	}
+	return allow
+}
+
+// check returns an error if no password is set.
+func (e *Encryption) check() error {
+	if e.OwnerPassword == "" && e.UserPassword == "" {
+		return fmt.Errorf("encryption requires an owner or user password")
+	}
+	return nil
+}
+
+// args returns the pdftk output arguments.
+func (e *Encryption) args() ([]string, error) {
+	err := e.check()
+	if err != nil {
+		return nil, err
+	}
+
+	var args []string
+	switch e.Strength {
+	case Encrypt128Bit:
+		args = append(args, "encrypt_128bit")
+	case Encrypt40Bit:
+		args = append(args, "encrypt_40bit")
+	default:
+		return nil, fmt.Errorf("invalid encryption strength: %d", e.Strength)
+	}
+
+	if e.OwnerPassword != "" {
+		args = append(args, "owner_pw", e.OwnerPassword)
+	}
+	if e.UserPassword != "" {
+		args = append(args, "user_pw", e.UserPassword)
+	}
+	if allow := e.allow(); len(allow) > 0 {
+		args = append(args, "allow")
+		args = append(args, allow...)
+	}
+	return args, nil
+}
+
+func (e *Encryption) pass(ctx context.Context, tmpDir, inputFile, outputFile string) error {
+	// pdftk does not support AES-256, so qpdf or pdfcpu is used instead.
+	if e.Strength == EncryptAES256 {
+		err := e.check()
+		if err != nil {
+			return err
+		}
+		if checkQpdf() != nil {
+			return e.encryptPdfcpu(inputFile, outputFile)
+		}
+		return e.encryptQpdf(ctx, tmpDir, inputFile, outputFile)
+	}
+
+	encArgs, err := e.args()
+	if err != nil {
+		return err
+	}
+
+	// Fall back to pdfcpu if pdftk is not installed.
+	if checkPdftk() != nil {
+		return e.encryptPdfcpu(inputFile, outputFile)
+	}
+
+	args := append([]string{inputFile, "output", outputFile}, encArgs...)
+	err = runPdftk(ctx, tmpDir, args...)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt PDF: %w", err)
+	}
+	return nil
+}
+
+// pdfcpuPermissions maps the pdftk allow keywords to the pdfcpu permissions.
+var pdfcpuPermissions = map[string]model.PermissionFlags{
+	"Printing":          model.PermissionPrintRev2 | model.PermissionPrintRev3,
+	"DegradedPrinting":  model.PermissionPrintRev2,
+	"ModifyContents":    model.PermissionModify,
+	"Assembly":          model.PermissionAssembleRev3,
+	"CopyContents":      model.PermissionExtract,
+	"ScreenReaders":     model.PermissionExtractRev3,
+	"ModifyAnnotations": model.PermissionModAnnFillForm,
+	"FillIn":            model.PermissionFillRev3,
+	"AllFeatures":       model.PermissionsAll,
+}
+
+// encryptPdfcpu encrypts the input file with pdfcpu.
+func (e *Encryption) encryptPdfcpu(inputFile, outputFile string) error {
+	keyLength := 128
+	switch e.Strength {
+	case Encrypt40Bit:
+		keyLength = 40
+	case EncryptAES256:
+		keyLength = 256
+	}
+
+	ownerPW, err := e.ownerPassword()
+	if err != nil {
+		return err
+	}
+
+	conf := pdfcpuReadConfig("")
+	conf.UserPW = e.UserPassword
+	conf.OwnerPW = ownerPW
+	conf.EncryptUsingAES = e.Strength == EncryptAES256
+	conf.EncryptKeyLength = keyLength
+	conf.Permissions = model.PermissionsNone
+	for _, a := range e.allow() {
+		p, ok := pdfcpuPermissions[a]
+		if !ok {
+			return fmt.Errorf("invalid encryption permission: '%s'", a)
+		}
+		conf.Permissions |= p
+	}
+
+	err = api.EncryptFile(inputFile, outputFile, conf)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt PDF: pdfcpu error: %w", err)
+	}
+	return nil
+}
+
+// ownerPassword returns the owner password. Unlike pdftk, qpdf and
+// pdfcpu require one, so a random owner password is generated if only
+// the user password is set. This keeps the permissions enforced.
+func (e *Encryption) ownerPassword() (string, error) {
+	if e.OwnerPassword != "" {
+		return e.OwnerPassword, nil
+	}
+
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate owner password: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// encryptQpdf encrypts the input file with AES-256 using qpdf.
+// qpdf permits everything by default, so all restrictions are passed.
+func (e *Encryption) encryptQpdf(ctx context.Context, tmpDir, inputFile, outputFile string) error {
+	restrictions, err := e.qpdfRestrictions()
+	if err != nil {
+		return err
+	}
+
+	ownerPW, err := e.ownerPassword()
+	if err != nil {
+		return err
+	}
+
+	args := []string{"--encrypt", e.UserPassword, ownerPW, "256"}
+	args = append(args, restrictions...)
+	args = append(args, "--", inputFile, outputFile)
+
+	err = runQpdf(ctx, tmpDir, args...)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt PDF: %w", err)
+	}
+	return nil
+}
+
+// qpdfRestrictions maps the allow keywords to the qpdf restriction flags.
+func (e *Encryption) qpdfRestrictions() ([]string, error) {
+	printing := "none"
+	allowed := map[string]bool{}
+	for _, a := range e.allow() {
+		switch a {
+		case "Printing":
+			printing = "full"
+		case "DegradedPrinting":
+			if printing == "none" {
+				printing = "low"
+			}
+		case "ModifyContents":
+			allowed["modify-other"] = true
+		case "Assembly":
+			allowed["assemble"] = true
+		case "CopyContents":
+			allowed["extract"] = true
+		case "ScreenReaders":
+			allowed["accessibility"] = true
+		case "ModifyAnnotations":
+			allowed["annotate"] = true
+			allowed["form"] = true
+		case "FillIn":
+			allowed["form"] = true
+		case "AllFeatures":
+			printing = "full"
+			for _, r := range qpdfFlags {
+				allowed[r] = true
+			}
+		default:
+			return nil, fmt.Errorf("invalid encryption permission: '%s'", a)
+		}
+	}
+
+	args := []string{"--print=" + printing}
+	for _, r := range qpdfFlags {
+		v := "n"
+		if allowed[r] {
+			v = "y"
+		}
+		args = append(args, "--"+r+"="+v)
+	}
+	return args, nil
+}
+
+// qpdfFlags are the qpdf restriction flags besides printing.
+var qpdfFlags = []string{"modify-other", "assemble", "extract", "accessibility", "annotate", "form"}
+
+// Decrypt removes the encryption of the input PDF file opened with the
+// owner or user password and writes the result to the destination file,
+// e.g. to normalize password protected templates once and cache them for
+// repeated filling. An existing destination file is replaced.
+// The decryption is done with the pdftk utility or with pdfcpu
+// if pdftk is not installed.
+func Decrypt(inputPDFFile, destPDFFile, password string) error {
+	return DecryptContext(context.Background(), inputPDFFile, destPDFFile, password)
+}
+
+// DecryptContext is like Decrypt, but the context is used to cancel
+// the spawned external processes.
+func DecryptContext(ctx context.Context, inputPDFFile, destPDFFile, password string) (err error) {
+	// Get the absolute paths.
+	destPDFFile, err = filepath.Abs(destPDFFile)
+	if err != nil {
+		return fmt.Errorf("failed to create the absolute path: %w", err)
+	}
+	inputs, err := absExistingFiles([]string{inputPDFFile})
+	if err != nil {
+		return err
+	}
+
+	// Create a temporary directory and remove it on defer again.
+	tmpDir, err := createTempDir("")
+	if err != nil {
+		return err
+	}
+	defer removeTempDir(tmpDir)
+
+	outputFile := filepath.Join(tmpDir, "output.pdf")
+	err = decrypt(ctx, tmpDir, inputs[0], outputFile, password)
+	if err != nil {
+		return err
+	}
+
+	return writeDestFile(outputFile, destPDFFile, true)
+}
+
+func decrypt(ctx context.Context, tmpDir, inputFile, outputFile, password string) error {
+	// Fall back to pdfcpu if pdftk is not installed.
+	if checkPdftk() != nil {
+		// pdfcpu does not support cancellation, so check at least once.
+		err := ctx.Err()
+		if err != nil {
+			return err
+		}
+
+		err = api.DecryptFile(inputFile, outputFile, pdfcpuReadConfig(password))
+		if err != nil {
+			return fmt.Errorf("failed to decrypt PDF: pdfcpu error: %w", err)
+		}
+		return nil
+	}
+
+	args := append(pdftkInput(inputFile, password), "output", outputFile)
+	err := runPdftk(ctx, tmpDir, args...)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt PDF: %w", err)
+	}
+	return nil
+}
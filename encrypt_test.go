@@ -0,0 +1,45 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import "testing"
+
+func TestOwnerPassword(t *testing.T) {
+	e := &Encryption{OwnerPassword: "owner", UserPassword: "user"}
+	pw, err := e.ownerPassword()
+	if err != nil {
+		t.Fatal(err)
+	} else if pw != "owner" {
+		t.Fatalf("got owner password %q, want %q", pw, "owner")
+	}
+
+	// A random owner password is generated for each encryption.
+	e = &Encryption{UserPassword: "user"}
+	pw1, err := e.ownerPassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pw2, err := e.ownerPassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pw1) != 32 || pw1 == pw2 || pw1 == e.UserPassword {
+		t.Fatalf("got owner passwords %q and %q", pw1, pw2)
+	}
+}
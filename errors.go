@@ -0,0 +1,173 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrPdftkNotFound is returned if the pdftk utility is not installed.
+	ErrPdftkNotFound = errors.New("pdftk utility is not installed")
+	// ErrQpdfNotFound is returned if the qpdf utility is not installed.
+	ErrQpdfNotFound = errors.New("qpdf utility is not installed")
+	// ErrGhostscriptNotFound is returned if the ghostscript utility is not installed.
+	ErrGhostscriptNotFound = errors.New("ghostscript utility is not installed")
+	// ErrPdftoppmNotFound is returned if the pdftoppm utility is not installed.
+	ErrPdftoppmNotFound = errors.New("pdftoppm utility is not installed")
+	// ErrPyhankoNotFound is returned if the pyhanko utility is not installed.
+	ErrPyhankoNotFound = errors.New("pyhanko utility is not installed")
+	// ErrLibreOfficeNotFound is returned if LibreOffice is not installed.
+	ErrLibreOfficeNotFound = errors.New("libreoffice is not installed")
+
+	// ErrTemplateNotFound is returned if the form PDF file
+	// or another input file does not exist.
+	ErrTemplateNotFound = errors.New("PDF file does not exist")
+	// ErrDestinationExists is returned if the destination file already
+	// exists and overwriting is disabled.
+	ErrDestinationExists = errors.New("destination PDF file already exists")
+)
+
+// ExecError is returned if an external utility fails.
+type ExecError struct {
+	// Name is the name of the utility, e.g. pdftk.
+	Name string
+	// Args are the command line arguments. Passwords are redacted.
+	Args []string
+	// Stderr is the error output of the utility.
+	Stderr string
+	// ExitCode is the exit code of the utility or -1 if it was not started.
+	ExitCode int
+	// Err is the underlying error.
+	Err error
+}
+
+// Error returns the error output of the utility or the underlying error
+// if the output is empty.
+func (e *ExecError) Error() string {
+	if s := strings.TrimSpace(e.Stderr); s != "" {
+		return s
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *ExecError) Unwrap() error {
+	return e.Err
+}
+
+// redactedArg replaces passwords within redacted arguments.
+const redactedArg = "REDACTED"
+
+// redactArgs returns a copy of the command line arguments with the
+// passwords of pdftk and qpdf replaced.
+func redactArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	copy(redacted, args)
+
+	for i := 0; i < len(redacted); i++ {
+		// Number of following arguments holding passwords.
+		n := 0
+		switch arg := redacted[i]; {
+		case arg == "input_pw" || arg == "owner_pw" || arg == "user_pw":
+			n = 1
+		case arg == "--encrypt":
+			// The user and owner passwords precede the key length.
+			n = 2
+		case strings.HasPrefix(arg, "--password="):
+			redacted[i] = "--password=" + redactedArg
+		}
+		for ; n > 0 && i+1 < len(redacted); n-- {
+			i++
+			redacted[i] = redactedArg
+		}
+	}
+	return redacted
+}
+
+// UnknownFieldsError is returned in strict mode if form keys
+// do not match any field of the form PDF.
+type UnknownFieldsError struct {
+	// Keys are the sorted unknown form keys.
+	Keys []string
+	// Suggestions map unknown keys to similar field names, if any.
+	Suggestions map[string]string
+}
+
+func (e *UnknownFieldsError) Error() string {
+	keys := make([]string, len(e.Keys))
+	for i, key := range e.Keys {
+		keys[i] = key
+		if s, ok := e.Suggestions[key]; ok {
+			keys[i] += fmt.Sprintf(" (did you mean '%s'?)", s)
+		}
+	}
+	return fmt.Sprintf("unknown form fields: %s", strings.Join(keys, ", "))
+}
+
+// MissingFieldsError is returned if required fields stay empty.
+type MissingFieldsError struct {
+	// Fields are the names of the empty required fields.
+	Fields []string
+}
+
+func (e *MissingFieldsError) Error() string {
+	return fmt.Sprintf("required form fields are empty: %s", strings.Join(e.Fields, ", "))
+}
+
+// MaxLenError is returned if values exceed the maximum length
+// of their text fields and the MaxLenFail policy is set.
+type MaxLenError struct {
+	// Fields are the sorted names of the fields with too long values.
+	Fields []string
+}
+
+func (e *MaxLenError) Error() string {
+	return fmt.Sprintf("form values exceed the maximum field length: %s", strings.Join(e.Fields, ", "))
+}
+
+// RadioValueError is returned if a RadioValue does not match
+// the export value of any radio button of its group.
+type RadioValueError struct {
+	// Group is the name of the radio group.
+	Group string
+	// Value is the invalid export value.
+	Value string
+	// Options are the export values of the radio buttons.
+	Options []string
+}
+
+func (e *RadioValueError) Error() string {
+	return fmt.Sprintf("invalid value '%s' of radio group '%s': valid values are %s",
+		e.Value, e.Group, strings.Join(e.Options, ", "))
+}
+
+// VerifyError is returned by the Verify option if fields of the
+// filled PDF do not contain the form values.
+type VerifyError struct {
+	// Fields are the sorted names of the fields with missing or
+	// different values.
+	Fields []string
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("filled PDF does not contain the values of the fields: %s", strings.Join(e.Fields, ", "))
+}
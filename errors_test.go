@@ -0,0 +1,66 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRedactArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "no passwords",
+			args: []string{"form.pdf", "fill_form", "data.fdf", "output", "out.pdf"},
+			want: []string{"form.pdf", "fill_form", "data.fdf", "output", "out.pdf"},
+		},
+		{
+			name: "pdftk passwords",
+			args: []string{"form.pdf", "input_pw", "secret", "output", "out.pdf", "owner_pw", "owner", "user_pw", "user"},
+			want: []string{"form.pdf", "input_pw", "REDACTED", "output", "out.pdf", "owner_pw", "REDACTED", "user_pw", "REDACTED"},
+		},
+		{
+			name: "qpdf encrypt",
+			args: []string{"--encrypt", "user", "owner", "256", "--", "in.pdf", "out.pdf"},
+			want: []string{"--encrypt", "REDACTED", "REDACTED", "256", "--", "in.pdf", "out.pdf"},
+		},
+		{
+			name: "qpdf password",
+			args: []string{"--password=secret", "--linearize", "in.pdf", "out.pdf"},
+			want: []string{"--password=REDACTED", "--linearize", "in.pdf", "out.pdf"},
+		},
+		{
+			name: "truncated",
+			args: []string{"form.pdf", "input_pw"},
+			want: []string{"form.pdf", "input_pw"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := redactArgs(tt.args)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
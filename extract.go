@@ -0,0 +1,136 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"sort"
+)
+
+// ExtractValues returns the current values of the form fields of an
+// already filled PDF file, e.g. to ingest a form edited with a viewer.
+// The values are strings. Buttons have their export value or "Off".
+// Push buttons and signature fields have no value and are skipped.
+// The returned form can be passed to Fill again.
+// The InputPassword option is used for password protected PDF files and
+// TempDir for the intermediate files, the other options are ignored.
+// The pdftk utility is required.
+func ExtractValues(pdfFile string, options ...Options) (Form, error) {
+	return ExtractValuesContext(context.Background(), pdfFile, options...)
+}
+
+// ExtractValuesContext is like ExtractValues, but the context is used to cancel
+// the spawned external processes.
+func ExtractValuesContext(ctx context.Context, pdfFile string, options ...Options) (Form, error) {
+	fields, err := GetFieldsContext(ctx, pdfFile, options...)
+	if err != nil {
+		return nil, err
+	}
+	return fieldValues(fields), nil
+}
+
+// fieldValues returns the values of the fields as form.
+func fieldValues(fields []Field) Form {
+	form := make(Form, len(fields))
+	for _, f := range fields {
+		if f.Type == fieldTypeSignature || f.Flags.Pushbutton {
+			continue
+		}
+
+		value := f.Value
+		if value == "" && f.Type == fieldTypeButton {
+			value = buttonOffState
+		}
+		form[f.Name] = value
+	}
+	return form
+}
+
+// FieldChange is a field value difference between two PDFs.
+type FieldChange struct {
+	// Name is the fully qualified field name.
+	Name string
+	// Old is the value within the first PDF.
+	Old string
+	// New is the value within the second PDF.
+	New string
+}
+
+// FieldsDiff lists the field value differences between two PDFs.
+// All changes are sorted by the field names.
+type FieldsDiff struct {
+	// Added are the fields which only exist in the second PDF.
+	Added []FieldChange
+	// Removed are the fields which only exist in the first PDF.
+	Removed []FieldChange
+	// Changed are the fields with different values.
+	Changed []FieldChange
+}
+
+// Empty returns whether both PDFs have the same field values.
+func (d *FieldsDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// CompareFields compares the field values of the PDF files a and b,
+// e.g. to verify a fill or to audit edits between document versions.
+// The values are read like with ExtractValues. The options apply to both files.
+func CompareFields(a, b string, options ...Options) (*FieldsDiff, error) {
+	return CompareFieldsContext(context.Background(), a, b, options...)
+}
+
+// CompareFieldsContext is like CompareFields, but the context is used to cancel
+// the spawned external processes.
+func CompareFieldsContext(ctx context.Context, a, b string, options ...Options) (*FieldsDiff, error) {
+	valuesA, err := ExtractValuesContext(ctx, a, options...)
+	if err != nil {
+		return nil, err
+	}
+	valuesB, err := ExtractValuesContext(ctx, b, options...)
+	if err != nil {
+		return nil, err
+	}
+	return compareValues(valuesA, valuesB), nil
+}
+
+// compareValues compares the extracted string values.
+func compareValues(a, b Form) *FieldsDiff {
+	d := &FieldsDiff{}
+	for name, va := range a {
+		old := formatValue(va)
+		vb, ok := b[name]
+		if !ok {
+			d.Removed = append(d.Removed, FieldChange{Name: name, Old: old})
+		} else if n := formatValue(vb); n != old {
+			d.Changed = append(d.Changed, FieldChange{Name: name, Old: old, New: n})
+		}
+	}
+	for name, vb := range b {
+		if _, ok := a[name]; !ok {
+			d.Added = append(d.Added, FieldChange{Name: name, New: formatValue(vb)})
+		}
+	}
+
+	for _, changes := range [][]FieldChange{d.Added, d.Removed, d.Changed} {
+		sort.Slice(changes, func(i, j int) bool {
+			return changes[i].Name < changes[j].Name
+		})
+	}
+	return d
+}
@@ -0,0 +1,178 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/gdamore/encoding"
+)
+
+var (
+	latin1Encoder = encoding.ISO8859_1.NewEncoder()
+
+	literalStringEscaper = strings.NewReplacer(
+		"\\", "\\\\",
+		"(", "\\(",
+		")", "\\)",
+		"\r", "\\r",
+	)
+)
+
+// Encoding defines how the form values are encoded in the fdf data.
+type Encoding int
+
+const (
+	// EncodingUTF16 encodes the values as UTF-16BE strings.
+	// All Unicode characters are supported. This is the default.
+	EncodingUTF16 Encoding = iota
+	// EncodingLatin1 encodes the values as Latin-1 strings.
+	// Other characters are replaced.
+	EncodingLatin1
+)
+
+// writeFdf writes the form values as fdf data to w.
+// The fields are written sorted by their names.
+func writeFdf(w io.Writer, form Form, enc Encoding) error {
+	bw := bufio.NewWriter(w)
+
+	// Write the fdf header.
+	fmt.Fprintf(bw, "%s\n", fdfHeader)
+
+	// Write the form data.
+	keys := make([]string, 0, len(form))
+	for key := range form {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		name, err := encodeString(key, enc)
+		if err != nil {
+			return fmt.Errorf("failed to encode field name '%s': %w", key, err)
+		}
+		value, err := encodeFdfValue(form[key], enc)
+		if err != nil {
+			return fmt.Errorf("failed to encode value of field '%s': %w", key, err)
+		}
+
+		// Rich text fields also get the rich text value.
+		var rich string
+		if rt, ok := form[key].(RichText); ok {
+			rich, err = encodeString(rt.richValue(), enc)
+			if err != nil {
+				return fmt.Errorf("failed to encode rich text value of field '%s': %w", key, err)
+			}
+			rich = " /RV " + rich
+		}
+
+		fmt.Fprintf(bw, "<< /T %s /V %s%s >>\n", name, value, rich)
+	}
+
+	// Write the fdf footer.
+	fmt.Fprintf(bw, "%s\n", fdfFooter)
+
+	// Flush everything.
+	return bw.Flush()
+}
+
+// encodeFdfValue encodes the value as PDF string. The values of
+// multi-select list boxes are encoded as array of strings.
+func encodeFdfValue(value interface{}, enc Encoding) (string, error) {
+	values, ok := value.([]string)
+	if !ok {
+		return encodeString(formatValue(value), enc)
+	}
+
+	parts := make([]string, len(values))
+	for i, v := range values {
+		s, err := encodeString(v, enc)
+		if err != nil {
+			return "", err
+		}
+		parts[i] = s
+	}
+	return "[" + strings.Join(parts, " ") + "]", nil
+}
+
+// encodeString encodes s as PDF string.
+// With the UTF-16 encoding, ASCII strings are kept as literal strings.
+func encodeString(s string, enc Encoding) (string, error) {
+	switch enc {
+	case EncodingUTF16:
+		if isASCII(s) {
+			return literalString(s), nil
+		}
+		return utf16String(s), nil
+	case EncodingLatin1:
+		l, err := latin1Encoder.String(s)
+		if err != nil {
+			return "", fmt.Errorf("failed to convert string to Latin-1")
+		}
+		return literalString(l), nil
+	default:
+		return "", fmt.Errorf("invalid encoding: %d", enc)
+	}
+}
+
+// literalString returns s as escaped PDF literal string.
+func literalString(s string) string {
+	return "(" + literalStringEscaper.Replace(s) + ")"
+}
+
+// utf16String returns s as UTF-16BE PDF hex string with byte order mark.
+func utf16String(s string) string {
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, c := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", c)
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+const fdfHeader = `%FDF-1.2
+%,,oe"
+1 0 obj
+<<
+/FDF << /Fields [`
+
+const fdfFooter = `]
+>>
+>>
+endobj
+trailer
+<<
+/Root 1 0 R
+>>
+%%EOF`
@@ -0,0 +1,425 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	fieldTypeText      = "Text"
+	fieldTypeButton    = "Button"
+	fieldTypeChoice    = "Choice"
+	fieldTypeSignature = "Signature"
+
+	buttonOffState = "Off"
+)
+
+// Field represents a form field of a PDF.
+type Field struct {
+	// Type is the field type: Text, Button, Choice or Signature.
+	Type string
+	// Name is the fully qualified field name.
+	Name string
+	// NameAlt is the alternate field name, which is shown as tooltip.
+	NameAlt string
+	// Flags are the decoded field flags.
+	Flags FieldFlags
+	// Justification of the field text: Left, Center or Right.
+	Justification string
+	// Options are the export values of buttons and the options of choices.
+	Options []string
+	// Value is the current field value.
+	Value string
+	// DefaultValue is the value the field is reset to.
+	DefaultValue string
+	// MaxLen is the maximum length of text field values or 0 if unlimited.
+	MaxLen int
+	// Page is the page number of the first widget of the field starting
+	// with 1. It is 0 if the placement of the field is unknown.
+	Page int
+	// Rect is the rectangle of the first widget of the field.
+	Rect Rect
+	// Widgets are the placements of all widgets of the field,
+	// e.g. of the buttons of a radio group.
+	Widgets []FieldWidget
+}
+
+// FieldWidget is the placement of a form field widget on a page.
+type FieldWidget struct {
+	// Page is the page number starting with 1.
+	Page int
+	// Rect is the widget rectangle in PDF user space units (points).
+	Rect Rect
+}
+
+// GetFields returns the form fields of the PDF file including their
+// placement on the pages. The InputPassword option is used for password
+// protected PDF files and TempDir for the intermediate files, the other
+// options are ignored. The pdftk utility is required.
+func GetFields(pdfFile string, options ...Options) ([]Field, error) {
+	return GetFieldsContext(context.Background(), pdfFile, options...)
+}
+
+// GetFieldsContext is like GetFields, but the context is used to cancel
+// the spawned external processes.
+func GetFieldsContext(ctx context.Context, pdfFile string, options ...Options) ([]Field, error) {
+	inputs, err := absExistingFiles([]string{pdfFile})
+	if err != nil {
+		return nil, err
+	}
+	return getFields(ctx, fileSource(inputs[0]), options...)
+}
+
+// GetFieldsFromReader is like GetFields, but reads the PDF from r.
+// The PDF is written to a temporary file, which is removed again afterwards.
+func GetFieldsFromReader(r io.Reader, options ...Options) ([]Field, error) {
+	return GetFieldsFromReaderContext(context.Background(), r, options...)
+}
+
+// GetFieldsFromReaderContext is like GetFieldsFromReader, but the context
+// is used to cancel the spawned external processes.
+func GetFieldsFromReaderContext(ctx context.Context, r io.Reader, options ...Options) ([]Field, error) {
+	return getFields(ctx, readerSource(r), options...)
+}
+
+// GetFieldsFromBytes is like GetFields, but reads the PDF from data.
+func GetFieldsFromBytes(data []byte, options ...Options) ([]Field, error) {
+	return GetFieldsFromBytesContext(context.Background(), data, options...)
+}
+
+// GetFieldsFromBytesContext is like GetFieldsFromBytes, but the context
+// is used to cancel the spawned external processes.
+func GetFieldsFromBytesContext(ctx context.Context, data []byte, options ...Options) ([]Field, error) {
+	return GetFieldsFromReaderContext(ctx, bytes.NewReader(data), options...)
+}
+
+func getFields(ctx context.Context, src source, options ...Options) (fields []Field, err error) {
+	opts := getOptions(options)
+	ctx = opts.instrument(ctx)
+
+	ctx, span := opts.tracer().Start(ctx, "fillpdf.GetFields")
+	defer func() {
+		span.SetAttributes(attribute.Int("fillpdf.form.fields", len(fields)))
+		endSpan(span, err)
+	}()
+
+	err = checkPdftk()
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a temporary directory and remove it on defer again.
+	tmpDir, err := createTempDir(opts.TempDir)
+	if err != nil {
+		return nil, err
+	}
+	defer removeTempDir(tmpDir)
+
+	// Obtain the PDF file.
+	pdfFile, err := src(tmpDir)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err = dumpFields(ctx, tmpDir, pdfFile, opts.InputPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	// pdftk does not report the placement of the fields.
+	addFieldPlacements(fields, pdfFile, opts.InputPassword)
+	return fields, nil
+}
+
+// addFieldPlacements sets the pages and the rectangles of the fields.
+// They are omitted if the document can not be parsed with pdfcpu.
+func addFieldPlacements(fields []Field, pdfFile, password string) {
+	widgets, err := readWidgets(pdfFile, password)
+	if err != nil {
+		return
+	}
+
+	placements := make(map[string][]FieldWidget)
+	for _, w := range widgets {
+		placements[w.Name] = append(placements[w.Name], FieldWidget{Page: w.Page, Rect: w.Rect})
+	}
+	for i := range fields {
+		ws := placements[fields[i].Name]
+		if len(ws) == 0 {
+			continue
+		}
+		fields[i].Page = ws[0].Page
+		fields[i].Rect = ws[0].Rect
+		fields[i].Widgets = ws
+	}
+}
+
+// GetSignatureFields returns the signature fields of the PDF file.
+// Signature images can be placed into them with SignatureImage form values.
+func GetSignatureFields(pdfFile string, options ...Options) ([]Field, error) {
+	return GetSignatureFieldsContext(context.Background(), pdfFile, options...)
+}
+
+// GetSignatureFieldsContext is like GetSignatureFields, but the context
+// is used to cancel the spawned external processes.
+func GetSignatureFieldsContext(ctx context.Context, pdfFile string, options ...Options) ([]Field, error) {
+	fields, err := GetFieldsContext(ctx, pdfFile, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	var sigFields []Field
+	for _, f := range fields {
+		if f.Type == fieldTypeSignature {
+			sigFields = append(sigFields, f)
+		}
+	}
+	return sigFields, nil
+}
+
+// onState returns the export value of a checked checkbox.
+// An empty string is returned if the field has none or is ambiguous.
+func (f Field) onState() string {
+	var on string
+	for _, o := range f.Options {
+		if o == buttonOffState {
+			continue
+		} else if on != "" {
+			return ""
+		}
+		on = o
+	}
+	return on
+}
+
+// dumpFields returns the form fields of the PDF file reported by pdftk.
+// The password is required for password protected PDF files only.
+func dumpFields(ctx context.Context, dir, pdfFile, password string) ([]Field, error) {
+	args := append(pdftkInput(pdfFile, password), "dump_data_fields_utf8")
+	out, err := runPdftkOutput(ctx, dir, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseFields(out)
+}
+
+// parseFields parses the output of pdftk's dump_data_fields.
+// Fields are separated by '---' lines, the attributes are key value pairs.
+func parseFields(data []byte) (fields []Field, err error) {
+	var cur *Field
+
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		line := s.Text()
+		if line == "---" {
+			fields = append(fields, Field{})
+			cur = &fields[len(fields)-1]
+			continue
+		} else if cur == nil {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			continue
+		}
+
+		switch key {
+		case "FieldType":
+			cur.Type = value
+		case "FieldName":
+			cur.Name = value
+		case "FieldFlags":
+			cur.Flags.Raw, err = strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid field flags of field '%s': %w", cur.Name, err)
+			}
+		case "FieldNameAlt":
+			cur.NameAlt = value
+		case "FieldJustification":
+			cur.Justification = value
+		case "FieldStateOption":
+			cur.Options = append(cur.Options, value)
+		case "FieldValue":
+			cur.Value = value
+		case "FieldValueDefault":
+			cur.DefaultValue = value
+		case "FieldMaxLength":
+			cur.MaxLen, err = strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid maximum length of field '%s': %w", cur.Name, err)
+			}
+		}
+	}
+	if err = s.Err(); err != nil {
+		return nil, err
+	}
+
+	// Decode the flags once the field types are known.
+	for i := range fields {
+		fields[i].Flags = decodeFieldFlags(fields[i].Type, fields[i].Flags.Raw)
+	}
+	return fields, nil
+}
+
+// hasBoolValues returns whether any form value is a bool.
+func hasBoolValues(form Form) bool {
+	for _, v := range form {
+		if _, ok := v.(bool); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveButtonValues returns a copy of the form with the values of button
+// fields mapped to their export values. A bool true maps to the on state
+// of a checkbox and false to the off state.
+func resolveButtonValues(form Form, fields []Field) Form {
+	buttons := make(map[string]Field)
+	for _, f := range fields {
+		if f.Type == fieldTypeButton && len(f.Options) > 0 {
+			buttons[f.Name] = f
+		}
+	}
+
+	resolved := make(Form, len(form))
+	for key, value := range form {
+		resolved[key] = value
+
+		f, ok := buttons[key]
+		if !ok {
+			continue
+		}
+
+		v, ok := value.(bool)
+		if !ok {
+			continue
+		} else if !v {
+			resolved[key] = buttonOffState
+		} else if on := f.onState(); on != "" {
+			resolved[key] = on
+		}
+	}
+	return resolved
+}
+
+// readFormFields reads the fields of the form PDF for the form checks.
+// The fields are read with pdftk, unless the Pdfcpu backend is used
+// and no field flags are required.
+func readFormFields(ctx context.Context, tmpDir, formPDFFile string, opts Options, flags bool) ([]Field, error) {
+	if opts.Backend == Pdfcpu && !flags {
+		pdfcpuFields, err := pdfcpuFormFields(formPDFFile, opts.InputPassword)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read form fields: %w", err)
+		}
+
+		fields := make([]Field, 0, len(pdfcpuFields))
+		for name, f := range pdfcpuFields {
+			fields = append(fields, Field{Name: name, Value: f.V})
+		}
+		sort.Slice(fields, func(i, j int) bool {
+			return fields[i].Name < fields[j].Name
+		})
+		return fields, nil
+	}
+
+	err := checkPdftk()
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := dumpFields(ctx, tmpDir, formPDFFile, opts.InputPassword)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read form fields: %w", err)
+	}
+	return fields, nil
+}
+
+// checkFormFields checks the form against the fields of the form PDF
+// as requested by the Strict and CheckRequired options.
+func checkFormFields(form Form, fields []Field, opts Options) error {
+	if opts.Strict {
+		unknown := unknownFields(form, fields)
+		if len(unknown) > 0 {
+			suggestions := make(map[string]string)
+			for _, key := range unknown {
+				if s := suggestField(key, fields); s != "" {
+					suggestions[key] = s
+				}
+			}
+			return &UnknownFieldsError{Keys: unknown, Suggestions: suggestions}
+		}
+	}
+	if opts.CheckRequired {
+		missing := missingRequiredFields(form, fields)
+		if len(missing) > 0 {
+			return &MissingFieldsError{Fields: missing}
+		}
+	}
+	return nil
+}
+
+// unknownFields returns the sorted form keys without a matching field.
+func unknownFields(form Form, fields []Field) []string {
+	names := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		names[f.Name] = true
+	}
+
+	var unknown []string
+	for key := range form {
+		if !names[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// missingRequiredFields returns the names of the required fields
+// which stay empty after filling. A field is empty if neither the form
+// nor the form PDF provide a value. An unchecked checkbox is empty.
+func missingRequiredFields(form Form, fields []Field) []string {
+	var missing []string
+	for _, f := range fields {
+		if !f.Flags.Required || f.Flags.ReadOnly {
+			continue
+		}
+
+		if v, ok := form[f.Name]; ok {
+			if isEmptyValue(v) {
+				missing = append(missing, f.Name)
+			}
+		} else if f.Value == "" || (f.Type == fieldTypeButton && f.Value == buttonOffState) {
+			missing = append(missing, f.Name)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,203 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Filler fills forms with a bounded number of concurrent workers.
+// Each fill spawns its own external processes, therefore unbounded
+// concurrent fills easily exhaust the available memory and CPU,
+// especially with the JVM based pdftk-java. Calls exceeding the
+// worker count are queued until a worker is available.
+// The fields of the form PDF files required by the form checks and the
+// checkbox values are cached per file until the file is modified, so
+// repeated fills of the same template do not read them again.
+// A Filler is safe for concurrent use.
+type Filler struct {
+	workers chan struct{}
+
+	mu     sync.Mutex
+	fields map[fieldsKey]cachedFields
+}
+
+// fieldsKey identifies the cached fields of a form PDF file.
+type fieldsKey struct {
+	file     string
+	password string
+	// pdfcpu is set for the fields read with pdfcpu, which lack the
+	// flags and options.
+	pdfcpu bool
+}
+
+// cachedFields are the fields of a form PDF file with the modification
+// time and size of the file they were read from.
+type cachedFields struct {
+	modTime time.Time
+	size    int64
+	fields  []Field
+}
+
+// NewFiller creates a new Filler with the given number of workers.
+// Defaults to the number of CPUs if workers is less than 1.
+func NewFiller(workers int) *Filler {
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	return &Filler{
+		workers: make(chan struct{}, workers),
+		fields:  make(map[fieldsKey]cachedFields),
+	}
+}
+
+// Workers returns the maximum number of concurrent fills.
+func (f *Filler) Workers() int {
+	return cap(f.workers)
+}
+
+// Fill is like FillContext, but waits for a free worker first.
+// The context cancels waiting within the queue.
+func (f *Filler) Fill(ctx context.Context, form Form, formPDFFile, destPDFFile string, options ...Options) error {
+	err := f.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer f.release()
+
+	inputs, err := absExistingFiles([]string{formPDFFile})
+	if err != nil {
+		return err
+	}
+	formPDFFile = inputs[0]
+
+	opts := getOptions(options)
+	opts.Backend = opts.backend()
+
+	fields, err := f.templateFields(ctx, formPDFFile, []Form{form}, opts)
+	if err != nil {
+		return err
+	}
+	if fields != nil && opts.Backend == Pdftk {
+		form = resolveButtonValues(form, fields)
+	}
+
+	return fill(ctx, form, fileSource(formPDFFile), destPDFFile, fields, nil, opts)
+}
+
+// FillReader is like FillReaderContext, but waits for a free worker first.
+// The context cancels waiting within the queue.
+func (f *Filler) FillReader(ctx context.Context, form Form, r io.Reader, destPDFFile string, options ...Options) error {
+	err := f.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer f.release()
+
+	return FillReaderContext(ctx, form, r, destPDFFile, options...)
+}
+
+// FillBytes is like FillBytesContext, but waits for a free worker first.
+// The context cancels waiting within the queue.
+func (f *Filler) FillBytes(ctx context.Context, form Form, data []byte, destPDFFile string, options ...Options) error {
+	err := f.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer f.release()
+
+	return FillBytesContext(ctx, form, data, destPDFFile, options...)
+}
+
+// acquire blocks until a worker is available or the context is done.
+func (f *Filler) acquire(ctx context.Context) error {
+	select {
+	case f.workers <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (f *Filler) release() {
+	<-f.workers
+}
+
+// templateFields returns the fields of the form PDF file if required by
+// the form checks or to map the bool values of the forms to the checkbox
+// export values with the pdftk backend. Otherwise nil is returned.
+// The fields are cached until the form PDF file is modified.
+func (f *Filler) templateFields(ctx context.Context, formPDFFile string, forms []Form, opts Options) ([]Field, error) {
+	// Repaired form PDFs are rewritten for each fill.
+	if opts.XFA || opts.Repair {
+		return nil, nil
+	}
+
+	required := opts.Strict || opts.CheckRequired || opts.FieldMatching != MatchExact
+	if !required && opts.Backend == Pdftk {
+		for _, form := range forms {
+			if hasBoolValues(form) {
+				required = true
+				break
+			}
+		}
+	}
+	if !required {
+		return nil, nil
+	}
+
+	fi, err := os.Stat(formPDFFile)
+	if err != nil {
+		return nil, err
+	}
+	key := fieldsKey{
+		file:     formPDFFile,
+		password: opts.InputPassword,
+		pdfcpu:   opts.Backend == Pdfcpu && !opts.CheckRequired,
+	}
+
+	f.mu.Lock()
+	c, ok := f.fields[key]
+	f.mu.Unlock()
+	if ok && c.modTime.Equal(fi.ModTime()) && c.size == fi.Size() {
+		return c.fields, nil
+	}
+
+	// Create a temporary directory and remove it on defer again.
+	tmpDir, err := createTempDir(opts.TempDir)
+	if err != nil {
+		return nil, err
+	}
+	defer removeTempDir(tmpDir)
+
+	fields, err := readFormFields(ctx, tmpDir, formPDFFile, opts, opts.CheckRequired)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	f.fields[key] = cachedFields{modTime: fi.ModTime(), size: fi.Size(), fields: fields}
+	f.mu.Unlock()
+	return fields, nil
+}
@@ -0,0 +1,112 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// FillFromFDF fills the PDF form with the FDF or XFDF form data read from r,
+// e.g. exported by Acrobat or other systems. The form data is passed to
+// pdftk as is. The Flatten, NeedAppearances, DropXFA, InputPassword,
+// Overwrite and TempDir options are used and the post processing options,
+// e.g. Overlays and Encryption, are applied afterwards. The options
+// processing the form values, e.g. Strict or Verify, are ignored.
+// The pdftk utility is required.
+func FillFromFDF(r io.Reader, formPDFFile, destPDFFile string, options ...Options) error {
+	return FillFromFDFContext(context.Background(), r, formPDFFile, destPDFFile, options...)
+}
+
+// FillFromFDFContext is like FillFromFDF, but the context is used to cancel
+// the fill process and the spawned external processes.
+func FillFromFDFContext(ctx context.Context, r io.Reader, formPDFFile, destPDFFile string, options ...Options) (err error) {
+	opts := getOptions(options)
+	ctx = opts.instrument(ctx)
+
+	// Get the absolute paths.
+	destPDFFile, err = filepath.Abs(destPDFFile)
+	if err != nil {
+		return fmt.Errorf("failed to create the absolute path: %w", err)
+	}
+	inputs, err := absExistingFiles([]string{formPDFFile})
+	if err != nil {
+		return err
+	}
+
+	err = checkPdftk()
+	if err != nil {
+		return err
+	}
+
+	// Create a temporary directory and remove it on defer again.
+	tmpDir, err := createTempDir(opts.TempDir)
+	if err != nil {
+		return err
+	}
+	defer removeTempDir(tmpDir)
+
+	// pdftk detects XFDF data by its content.
+	fdfFile := filepath.Join(tmpDir, "data.fdf")
+	err = writeFile(fdfFile, r)
+	if err != nil {
+		return fmt.Errorf("failed to write form data file: %w", err)
+	}
+
+	outputFile := filepath.Join(tmpDir, "output.pdf")
+	args := pdftkInput(inputs[0], opts.InputPassword)
+	args = append(args,
+		"fill_form", fdfFile,
+		"output", outputFile,
+	)
+	args = append(args, opts.pdftkFillFlags()...)
+	err = runPdftk(ctx, tmpDir, args...)
+	if err != nil {
+		return err
+	}
+
+	outputFile, err = runPasses(ctx, tmpDir, outputFile, opts.passes())
+	if err != nil {
+		return err
+	}
+
+	// On success, write the output file to the final destination.
+	return writeDestFile(outputFile, destPDFFile, opts.Overwrite)
+}
+
+// FillFromFDFFile is like FillFromFDF, but reads the FDF or XFDF
+// form data from the fdfFile.
+func FillFromFDFFile(fdfFile, formPDFFile, destPDFFile string, options ...Options) error {
+	return FillFromFDFFileContext(context.Background(), fdfFile, formPDFFile, destPDFFile, options...)
+}
+
+// FillFromFDFFileContext is like FillFromFDFFile, but the context is used
+// to cancel the fill process and the spawned external processes.
+func FillFromFDFFileContext(ctx context.Context, fdfFile, formPDFFile, destPDFFile string, options ...Options) error {
+	f, err := os.Open(fdfFile)
+	if err != nil {
+		return fmt.Errorf("failed to open form data file: %w", err)
+	}
+	defer f.Close()
+
+	return FillFromFDFContext(ctx, f, formPDFFile, destPDFFile, options...)
+}
@@ -19,25 +19,23 @@
 package fillpdf
 
 import (
-	"bufio"
+	"bytes"
+	"context"
 	"fmt"
-	"io/ioutil"
-	"log"
+	"io"
+	"log/slog"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"time"
 
-	"github.com/gdamore/encoding"
-)
-
-var (
-	// pdftk does not support UTF-8. To support at least some special characters,
-	// let's use the Latin-1 encoding.
-	latin1Encoder = encoding.ISO8859_1.NewEncoder()
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Form represents the PDF form.
-// This is a key value map.
+// This is a key value map. Values of nested maps are assigned to
+// the hierarchical field names, e.g. "applicant.address.street".
+// []string values select the options of multi-select list boxes.
 type Form map[string]interface{}
 
 // Options represents the options to alter the PDF filling process
@@ -46,6 +44,183 @@ type Options struct {
 	Overwrite bool
 	// Flatten will flatten the document making the form fields no longer editable
 	Flatten bool
+	// PreserveTags flattens with pdfcpu instead of the backend, keeping the
+	// logical structure of tagged PDFs intact, so the flattened PDF stays
+	// accessible to screen readers. The field appearances become the
+	// content of their structure elements. Ignored without Flatten.
+	PreserveTags bool
+	// TempDir is the directory in which the temporary working directory
+	// is created, e.g. a tmpfs or per tenant directory. Intermediate files
+	// are only accessible by the current user and are always removed again.
+	// Defaults to the system's temporary directory if empty.
+	TempDir string
+	// TracerProvider creates the OpenTelemetry spans of filling and
+	// reading fields, the post processing passes and the external commands.
+	// Defaults to the global tracer provider if nil.
+	TracerProvider trace.TracerProvider
+	// Metrics observes the fills and the external commands if set.
+	Metrics Metrics
+	// Logger logs the external commands with their durations and exit codes.
+	// Command arguments and form values are never logged. See WithLogger.
+	Logger *slog.Logger
+	// Backend fills the form fields. Defaults to the Pdftk backend if nil
+	// and to the Pdfcpu backend if pdftk is not installed.
+	Backend Backend
+	// Strict fails with an UnknownFieldsError if form keys do not match
+	// any field of the form PDF, e.g. because of typos. Without strict mode
+	// unknown keys are ignored silently. Ignored for XFA filling.
+	Strict bool
+	// FieldMatching defines how form keys are matched to the field names,
+	// e.g. to survive renamed fields of a new template version.
+	// Ignored for XFA filling.
+	FieldMatching FieldMatching
+	// MaxLen defines how string values exceeding the maximum length
+	// of their text fields are handled, e.g. for character boxed comb fields
+	// like IBAN boxes. Ignored for XFA filling.
+	MaxLen MaxLenPolicy
+	// CheckRequired fails with a MissingFieldsError if required fields
+	// stay empty after filling. Requires pdftk. Ignored for XFA filling.
+	CheckRequired bool
+	// Verify reparses the filled PDF and checks that its fields contain the
+	// form values. A VerifyError is returned for missing or different values.
+	// The values of fields removed by flattening and of XFA forms are not
+	// checked, image values are never checked.
+	Verify bool
+	// Encryption encrypts the filled PDF if set.
+	Encryption *Encryption
+	// Repair rewrites the form PDF before filling, e.g. templates exported
+	// by buggy generators which make pdftk fail. See RepairPDF.
+	Repair bool
+	// InputPassword is the owner or user password of a password protected form PDF.
+	InputPassword string
+	// Encoding of the form values passed to pdftk. Defaults to UTF-16.
+	Encoding Encoding
+	// Defaults also sets the filled values as default values of their
+	// fields, so the form stays editable and resetting it restores the
+	// filled values. Can not be combined with Flatten.
+	Defaults bool
+	// ReadOnly sets the ReadOnly flag of the filled fields, so viewers
+	// prevent editing while the field values stay extractable.
+	// It may be used instead of or in addition to Flatten.
+	// Ignored for XFA filling.
+	ReadOnly bool
+	// CheckboxOn is the export value of checked checkboxes, e.g. "Yes", "1",
+	// "On" or "X". If set, bool values are translated to it or to "Off"
+	// without reading the export values from the form PDF.
+	CheckboxOn string
+	// CheckboxStates map field names to the export value of their checked
+	// state, overriding CheckboxOn. Bool values of these fields and strings
+	// like "yes", "no", "on", "off", "true", "false", "1" and "0" are
+	// translated. Other strings are passed as is.
+	CheckboxStates map[string]string
+	// RepeatPattern expands slice values to numbered fields, e.g. the key
+	// "item" with a []string fills "item_1", "item_2" and so on for the
+	// pattern "{name}_{index}". The {name} placeholder is the form key and
+	// {index} the element number starting with 1. Slices are only expanded
+	// if set, therefore multi-select list values are not supported then.
+	RepeatPattern string
+	// TimeLayout is the layout of time.Time values, e.g. "02.01.2006"
+	// or "Jan 2, 2006". Defaults to DefaultTimeLayout if empty.
+	// Zero times are filled as empty values.
+	TimeLayout string
+	// FieldTimeLayouts overwrites the TimeLayout for single fields.
+	// The map keys are the field names.
+	FieldTimeLayouts map[string]string
+	// ValueEncoder is called for every form value except images and
+	// returns its textual representation, e.g. to centralize formatting,
+	// masking or translation. It replaces the built-in conversion of
+	// FieldValuer and time values. The name is the form key.
+	ValueEncoder func(name string, v interface{}) (string, error)
+	// Appearances override the font, font size and text color of fields
+	// by their names, e.g. if the template ships with unusable default
+	// appearances. The appearances of the filled fields are regenerated.
+	// Ignored for XFA filling.
+	Appearances map[string]AppearanceOptions
+	// Fonts are TrueType font files for values with characters missing
+	// from the WinAnsi encoding of the template fonts, e.g. Cyrillic, Greek
+	// or CJK. Each of these values is shown with the first font containing
+	// all of its characters, which is embedded as subset. The appearances
+	// of these values are single lines. Ignored for XFA filling.
+	Fonts []string
+	// NeedAppearances instructs viewers to regenerate the field appearances,
+	// so filled values are shown without clicking the fields first.
+	// It has no effect if the document is flattened, because flattening
+	// already burns the values into the page content.
+	// Supported by the pdftk backend only.
+	NeedAppearances bool
+	// DropXFA removes the XFA form of hybrid XFA and AcroForm documents.
+	// Viewers like Adobe Reader otherwise show the stale XFA form
+	// instead of the filled AcroForm values.
+	DropXFA bool
+	// XFA fills the XFA form of XFA only and hybrid documents instead of
+	// the AcroForm by injecting the values as XFA datasets. The dotted field
+	// names describe the data hierarchy, e.g. "form1.Page1.Name".
+	// The backend and Flatten are ignored, XFA forms can not be flattened.
+	XFA bool
+	// SyncXFA writes the values of the filled AcroForm fields of hybrid XFA
+	// and AcroForm documents into the XFA datasets, too, so viewers rendering
+	// the XFA form show the same data without DropXFA. The datasets are
+	// rebuilt from the field values. Ignored with DropXFA and XFA.
+	SyncXFA bool
+	// QRCodes are QR codes placed at fixed coordinates onto the pages
+	// of the filled PDF. Use QRCode form values to place them into fields.
+	QRCodes []QRCodeStamp
+	// Barcodes are linear barcodes placed at fixed coordinates onto the pages
+	// of the filled PDF. Use Barcode form values to place them into fields.
+	Barcodes []BarcodeStamp
+	// FlattenAnnotations burns the annotations other than form fields,
+	// e.g. stamps, ink drawings and comments, into the pages of the filled
+	// PDF, but keeps the form interactive. See FlattenAnnotations for details.
+	FlattenAnnotations bool
+	// Overlays are texts placed onto the pages of the filled PDF.
+	Overlays []Overlay
+	// Rotate sets the rotation of page ranges of the filled PDF,
+	// e.g. to correct landscape scanned templates. Requires pdftk.
+	Rotate []PageRotation
+	// Pages are the comma separated page ranges of the filled PDF to keep,
+	// e.g. "1-3,5" to drop the instruction pages of the template. The page
+	// ranges use the pdftk syntax, e.g. "4-end". Requires pdftk.
+	Pages string
+	// AppendPDFs are PDF files whose pages are appended after the pages of
+	// the filled PDF, e.g. terms and conditions. Requires pdftk.
+	AppendPDFs []string
+	// Bates stamps a sequential identifier onto every page if set.
+	Bates *Bates
+	// NUp imposes several pages of the filled PDF onto each sheet if set.
+	// See NUp for details.
+	NUp *NUp
+	// RemoveMetadata removes the document info entries and the XMP metadata
+	// of the filled PDF. See RemoveMetadata for details.
+	RemoveMetadata bool
+	// RemoveJavaScript removes the document level JavaScript and the
+	// JavaScript actions of the filled PDF, e.g. for recipients rejecting
+	// documents with scripts. See RemoveJavaScript for details.
+	RemoveJavaScript bool
+	// Sanitize removes JavaScript, launch, URI and other external actions,
+	// embedded files and external references of the filled PDF, so it is
+	// safe to distribute. See Sanitize for details. The Attachments are
+	// embedded afterwards.
+	Sanitize bool
+	// DocInfo sets the document info entries of the filled PDF,
+	// e.g. Title, Author, Subject and Keywords. Requires pdftk.
+	DocInfo map[string]string
+	// PDFA converts the filled PDF to PDF/A if set. Requires Ghostscript.
+	PDFA *PDFA
+	// Attachments are file paths embedded into the filled PDF as
+	// document attachments, e.g. the source data or supporting documents.
+	// Requires pdftk.
+	Attachments []string
+	// Optimize reduces the size of the filled PDF by removing unused objects,
+	// deduplicating fonts and images and compressing uncompressed streams.
+	Optimize bool
+	// Linearize optimizes the filled PDF for fast web view, so the first
+	// page renders before the download completes. Requires qpdf.
+	// Signing appends an incremental update, which invalidates the
+	// linearization without breaking the document.
+	Linearize bool
+	// Signature digitally signs the filled PDF if set.
+	// Signing can not be combined with encryption.
+	Signature *Signature
 }
 
 func defaultOptions() Options {
@@ -55,150 +230,336 @@ func defaultOptions() Options {
 	}
 }
 
-// Fill a PDF form with the specified form values and create a final filled PDF file.
-// The options parameter alters few aspects of the generation.
-func Fill(form Form, formPDFFile, destPDFFile string, options ...Options) (err error) {
-	// If the user provided the options we overwrite the defaults with the given struct.
-	opts := defaultOptions()
+// getOptions returns the user provided options or the default options.
+func getOptions(options []Options) Options {
 	if len(options) > 0 {
-		opts = options[0]
+		return options[0]
 	}
+	return defaultOptions()
+}
+
+// Fill a PDF form with the specified form values and create a final filled PDF file.
+// The options parameter alters few aspects of the generation.
+func Fill(form Form, formPDFFile, destPDFFile string, options ...Options) error {
+	return FillContext(context.Background(), form, formPDFFile, destPDFFile, options...)
+}
 
-	// Get the absolute paths.
+// FillContext is like Fill, but the context is used to cancel
+// the fill process and the spawned external processes.
+func FillContext(ctx context.Context, form Form, formPDFFile, destPDFFile string, options ...Options) (err error) {
+	// Get the absolute path.
 	formPDFFile, err = filepath.Abs(formPDFFile)
 	if err != nil {
-		return fmt.Errorf("failed to create the absolute path: %v", err)
-	}
-	destPDFFile, err = filepath.Abs(destPDFFile)
-	if err != nil {
-		return fmt.Errorf("failed to create the absolute path: %v", err)
+		return fmt.Errorf("failed to create the absolute path: %w", err)
 	}
 
 	// Check if the form file exists.
 	e, err := exists(formPDFFile)
 	if err != nil {
-		return fmt.Errorf("failed to check if form PDF file exists: %v", err)
+		return fmt.Errorf("failed to check if form PDF file exists: %w", err)
 	} else if !e {
-		return fmt.Errorf("form PDF file does not exists: '%s'", formPDFFile)
+		return fmt.Errorf("%w: '%s'", ErrTemplateNotFound, formPDFFile)
+	}
+
+	return fill(ctx, form, fileSource(formPDFFile), destPDFFile, nil, nil, options...)
+}
+
+// FillReader fills the PDF form read from r with the specified form values
+// and creates a final filled PDF file.
+// The form PDF is written to a temporary file, which is removed again afterwards.
+// The options parameter alters few aspects of the generation.
+func FillReader(form Form, r io.Reader, destPDFFile string, options ...Options) error {
+	return FillReaderContext(context.Background(), form, r, destPDFFile, options...)
+}
+
+// FillReaderContext is like FillReader, but the context is used to cancel
+// the fill process and the spawned external processes.
+func FillReaderContext(ctx context.Context, form Form, r io.Reader, destPDFFile string, options ...Options) error {
+	return fill(ctx, form, readerSource(r), destPDFFile, nil, nil, options...)
+}
+
+// FillBytes fills the PDF form contained in data with the specified form values
+// and creates a final filled PDF file.
+// The options parameter alters few aspects of the generation.
+func FillBytes(form Form, data []byte, destPDFFile string, options ...Options) error {
+	return FillBytesContext(context.Background(), form, data, destPDFFile, options...)
+}
+
+// FillBytesContext is like FillBytes, but the context is used to cancel
+// the fill process and the spawned external processes.
+func FillBytesContext(ctx context.Context, form Form, data []byte, destPDFFile string, options ...Options) error {
+	return FillReaderContext(ctx, form, bytes.NewReader(data), destPDFFile, options...)
+}
+
+// source provides the path to the form PDF file.
+// Sources which have to create the file, must create it within tmpDir.
+type source func(tmpDir string) (string, error)
+
+// fileSource returns a source for an existing form PDF file.
+func fileSource(path string) source {
+	return func(tmpDir string) (string, error) {
+		return path, nil
 	}
+}
 
-	// Check if the pdftk utility exists.
-	_, err = exec.LookPath("pdftk")
+// readerSource returns a source, which writes the form PDF read from r
+// into the temporary directory.
+func readerSource(r io.Reader) source {
+	return func(tmpDir string) (string, error) {
+		path := filepath.Clean(tmpDir + "/form.pdf")
+		err := writeFile(path, r)
+		if err != nil {
+			return "", fmt.Errorf("failed to write form PDF file: %w", err)
+		}
+		return path, nil
+	}
+}
+
+// fill fills the form PDF obtained from the source.
+// The form checks use the fields if not nil, which must have been read
+// by readFormFields from the same form PDF. Otherwise they are read again.
+// The fill outcome is stored in result if not nil.
+func fill(ctx context.Context, form Form, src source, destPDFFile string, fields []Field, result *FillResult, options ...Options) (err error) {
+	opts := getOptions(options)
+	ctx = opts.instrument(ctx)
+
+	// Observe the fill once it is done.
+	var outputBytes int64
+	defer func(start time.Time) { observeFill(opts.Metrics, start, outputBytes, err) }(time.Now())
+
+	ctx, span := opts.tracer().Start(ctx, "fillpdf.Fill", trace.WithAttributes(
+		attribute.Int("fillpdf.form.fields", len(form)),
+	))
+	defer func() { endSpan(span, err) }()
+
+	if opts.Signature != nil && opts.Encryption != nil {
+		return fmt.Errorf("signing an encrypted PDF is not supported")
+	} else if opts.PDFA != nil && opts.Encryption != nil {
+		return fmt.Errorf("PDF/A does not permit encryption")
+	} else if opts.Defaults && opts.Flatten {
+		return fmt.Errorf("default values require an editable form: disable flattening")
+	}
+
+	// Nested maps describe the field hierarchy and slices may
+	// describe repeated fields.
+	form, err = opts.prepareForm(form)
 	if err != nil {
-		return fmt.Errorf("pdftk utility is not installed!")
+		return err
 	}
 
-	// Create a temporary directory.
-	tmpDir, err := ioutil.TempDir("", "fillpdf-")
+	// Get the absolute path.
+	destPDFFile, err = filepath.Abs(destPDFFile)
 	if err != nil {
-		return fmt.Errorf("failed to create temporary directory: %v", err)
+		return fmt.Errorf("failed to create the absolute path: %w", err)
 	}
 
-	// Remove the temporary directory on defer again.
-	defer func() {
-		errD := os.RemoveAll(tmpDir)
-		// Log the error only.
-		if errD != nil {
-			log.Printf("fillpdf: failed to remove temporary directory '%s' again: %v", tmpDir, errD)
+	// Use the pdftk backend by default and fall back to pdfcpu.
+	opts.Backend = opts.backend()
+
+	// Create a temporary directory and remove it on defer again.
+	tmpDir, err := createTempDir(opts.TempDir)
+	if err != nil {
+		return err
+	}
+	defer removeTempDir(tmpDir)
+
+	// Obtain the form PDF file.
+	formPDFFile, err := src(tmpDir)
+	if err != nil {
+		return err
+	}
+	setFileSize(span, "fillpdf.input.bytes", formPDFFile)
+
+	// Rewrite damaged form PDF files before reading their fields.
+	if opts.Repair {
+		formPDFFile, err = repairFormFile(ctx, tmpDir, formPDFFile, opts.InputPassword)
+		if err != nil {
+			return err
 		}
-	}()
+	}
 
 	// Create the temporary output file path.
 	outputFile := filepath.Clean(tmpDir + "/output.pdf")
 
-	// Create the fdf data file.
-	fdfFile := filepath.Clean(tmpDir + "/data.fdf")
-	err = createFdfFile(form, fdfFile)
-	if err != nil {
-		return fmt.Errorf("failed to create fdf form data file: %v", err)
+	// Match the form keys and check them against the form fields.
+	if (opts.Strict || opts.CheckRequired || opts.FieldMatching != MatchExact || result != nil) && !opts.XFA {
+		if fields == nil {
+			fields, err = readFormFields(ctx, tmpDir, formPDFFile, opts, opts.CheckRequired)
+			if err != nil {
+				return err
+			}
+		}
+
+		form, err = matchFieldNames(form, fields, opts.FieldMatching)
+		if err != nil {
+			return err
+		}
+
+		err = checkFormFields(form, fields, opts)
+		if err != nil {
+			return err
+		}
+
+		if result != nil {
+			*result = newFillResult(form, fields)
+		}
 	}
 
-	// Create the pdftk command line arguments.
-	args := []string{
-		formPDFFile,
-		"fill_form", fdfFile,
-		"output", outputFile,
+	// Check the radio values against the export values of their groups.
+	if values := radioValues(form); len(values) > 0 && !opts.XFA {
+		err = checkRadioValues(values, formPDFFile, opts.InputPassword)
+		if err != nil {
+			return err
+		}
 	}
 
-	// If the user specified to flatten the output PDF we append the related parameter.
-	if opts.Flatten {
-		args = append(args, "flatten")
+	// Check the fields of the multiple values are list boxes.
+	if values := multiValues(form); len(values) > 0 && !opts.XFA {
+		err = checkMultiValues(values, formPDFFile, opts.InputPassword)
+		if err != nil {
+			return err
+		}
 	}
 
-	// Run the pdftk utility.
-	err = runCommandInPath(tmpDir, "pdftk", args...)
+	// Convert the values with a custom representation.
+	form, err = opts.encodeValues(form)
 	if err != nil {
-		return fmt.Errorf("pdftk error: %v", err)
+		return err
 	}
 
-	// Check if the destination file exists.
-	e, err = exists(destPDFFile)
-	if err != nil {
-		return fmt.Errorf("failed to check if destination PDF file exists: %v", err)
-	} else if e {
-		if !opts.Overwrite {
-			return fmt.Errorf("destination PDF file already exists: '%s'", destPDFFile)
+	// Fit the values into the text fields with a maximum length.
+	if opts.MaxLen != MaxLenIgnore && !opts.XFA {
+		form, err = fitMaxLen(form, formPDFFile, opts.InputPassword, opts.MaxLen)
+		if err != nil {
+			return err
 		}
+	}
 
-		err = os.Remove(destPDFFile)
+	// Override the default appearances before the backend regenerates them.
+	if len(opts.Appearances) > 0 && !opts.XFA {
+		formPDFFile, err = appearanceFormFile(tmpDir, formPDFFile, opts.InputPassword, opts.Appearances)
 		if err != nil {
-			return fmt.Errorf("failed to remove destination PDF file: %v", err)
+			return err
 		}
 	}
 
-	// On success, copy the output file to the final destination.
-	err = copyFile(outputFile, destPDFFile)
-	if err != nil {
-		return fmt.Errorf("failed to copy created output PDF to final destination: %v", err)
+	// The custom values of editable combo boxes, the appearances of
+	// values requiring the user fonts and the XFA datasets are set after
+	// filling, so flattening is postponed until then.
+	var passes []pass
+	fillOpts := opts
+	if !opts.XFA {
+		values, err := customComboValues(form, formPDFFile, opts.InputPassword)
+		if err != nil {
+			return fmt.Errorf("failed to read combo box options: %w", err)
+		}
+		if len(values) > 0 {
+			passes = append(passes, tracedPass("combos", comboValuesPass(values)))
+		}
+	}
+	if len(opts.Fonts) > 0 && !opts.XFA {
+		if values := fontValues(form); len(values) > 0 {
+			passes = append(passes, tracedPass("fonts", fontAppearancesPass(values, opts.Fonts)))
+		}
+	}
+	if opts.SyncXFA && !opts.DropXFA && !opts.XFA {
+		passes = append(passes, tracedPass("xfa", syncXFAPass))
+	}
+	if (len(passes) > 0 || opts.PreserveTags) && opts.Flatten && !opts.XFA {
+		fillOpts.Flatten = false
+		if opts.PreserveTags {
+			passes = append(passes, tracedPass("flatten", flattenTaggedPass))
+		} else {
+			passes = append(passes, tracedPass("flatten", flattenPass(opts.Backend)))
+		}
 	}
 
-	return nil
-}
+	// Images are stamped onto the filled PDF, so locate their fields
+	// before they are flattened.
+	var stamps []imageStamp
+	form, images := splitImages(form)
+	if len(images) > 0 {
+		widgets, err := readWidgets(formPDFFile, opts.InputPassword)
+		if err != nil {
+			return fmt.Errorf("failed to read form field widgets: %w", err)
+		}
+		stamps, err = imageStamps(images, widgets)
+		if err != nil {
+			return err
+		}
+	}
+	if len(opts.QRCodes) > 0 || len(opts.Barcodes) > 0 {
+		viewports, err := readViewports(formPDFFile, opts.InputPassword)
+		if err != nil {
+			return fmt.Errorf("failed to read page boxes: %w", err)
+		}
+		qrStamps, err := qrCodeStamps(opts.QRCodes, viewports)
+		if err != nil {
+			return err
+		}
+		codeStamps, err := barcodeStamps(opts.Barcodes, viewports)
+		if err != nil {
+			return err
+		}
+		stamps = append(append(stamps, qrStamps...), codeStamps...)
+	}
+	if len(stamps) > 0 {
+		passes = append(passes, tracedPass("images", stampImagesPass(stamps)))
+	}
 
-func createFdfFile(form Form, path string) error {
-	// Create the file.
-	file, err := os.Create(path)
+	// Fill the form.
+	if opts.XFA {
+		err = fillXFA(ctx, form, formPDFFile, outputFile, opts.InputPassword)
+		if err != nil {
+			return fmt.Errorf("failed to fill XFA form: %w", err)
+		}
+	} else {
+		// XFA only forms have no fields to fill.
+		err = checkNotXFAOnly(formPDFFile, opts.InputPassword)
+		if err != nil {
+			return err
+		}
+
+		err = opts.Backend.Fill(ctx, tmpDir, form, formPDFFile, outputFile, fillOpts)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Apply the post processing passes.
+	if opts.Defaults && !opts.XFA {
+		passes = append(passes, tracedPass("defaults", defaultValuesPass(form)))
+	}
+	if opts.ReadOnly && !opts.XFA {
+		passes = append(passes, tracedPass("readonly", readOnlyPass(form)))
+	}
+	passes = append(passes, opts.passes()...)
+	outputFile, err = runPasses(ctx, tmpDir, outputFile, passes)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
 
-	// Create a new writer.
-	w := bufio.NewWriter(file)
-
-	// Write the fdf header.
-	fmt.Fprintln(w, fdfHeader)
-
-	// Write the form data.
-	var valueStr string
-	for key, value := range form {
-		// Convert to Latin-1.
-		valueStr, err = latin1Encoder.String(fmt.Sprintf("%v", value))
+	// Reparse the filled PDF and check the values before handing it out.
+	if opts.Verify {
+		var values Form
+		if !opts.XFA {
+			values = form
+		}
+		err = verifyFilled(values, outputFile, opts.outputPassword(), opts.Flatten || opts.XFA)
 		if err != nil {
-			return fmt.Errorf("failed to convert string to Latin-1")
+			return err
 		}
-		fmt.Fprintf(w, "<< /T (%s) /V (%s)>>\n", key, valueStr)
+	}
+	if fi, err := os.Stat(outputFile); err == nil {
+		outputBytes = fi.Size()
+		span.SetAttributes(attribute.Int64("fillpdf.output.bytes", outputBytes))
 	}
 
-	// Write the fdf footer.
-	fmt.Fprintln(w, fdfFooter)
-
-	// Flush everything.
-	return w.Flush()
+	// On success, write the output file to the final destination.
+	return writeDestFile(outputFile, destPDFFile, opts.Overwrite)
 }
 
-const fdfHeader = `%FDF-1.2
-%,,oe"
-1 0 obj
-<<
-/FDF << /Fields [`
-
-const fdfFooter = `]
->>
->>
-endobj
-trailer
-<<
-/Root 1 0 R
->>
-%%EOF`
+// formatValue returns the textual representation of a form value.
+func formatValue(value interface{}) string {
+	return fmt.Sprintf("%v", value)
+}
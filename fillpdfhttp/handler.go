@@ -0,0 +1,257 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+// Package fillpdfhttp provides an HTTP handler serving PDF form fills,
+// e.g. as target of the fillpdf.RemoteBackend.
+package fillpdfhttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/desertbit/fillpdf"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+)
+
+// DefaultMaxRequestSize is the request size limit if none is set.
+const DefaultMaxRequestSize = 32 << 20
+
+// Handler fills PDF forms uploaded as multipart/form-data POST request
+// and responds with the filled PDF. The request parts are:
+//
+//	template  the form PDF file
+//	data      the form values as JSON object, see fillpdf.FormFromJSON
+//	options   the fill options as JSON object (optional)
+//
+// The options object supports the keys flatten, needAppearances, dropXFA,
+// inputPassword and encoding ("utf16" or "latin1"). They overwrite the
+// corresponding handler options.
+//
+// A wrong input password is responded with 400 Bad Request and invalid
+// form values, e.g. unknown fields in strict mode, with 422 Unprocessable
+// Entity. Other fill errors are logged and responded with 500 Internal
+// Server Error without details.
+type Handler struct {
+	// Options are the base fill options, e.g. the backend.
+	Options fillpdf.Options
+	// MaxRequestSize limits the request size in bytes.
+	// Defaults to DefaultMaxRequestSize if zero.
+	MaxRequestSize int64
+}
+
+// requestOptions are the fill options of a request.
+type requestOptions struct {
+	Flatten         *bool   `json:"flatten"`
+	NeedAppearances *bool   `json:"needAppearances"`
+	DropXFA         *bool   `json:"dropXFA"`
+	InputPassword   *string `json:"inputPassword"`
+	Encoding        *string `json:"encoding"`
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	maxSize := h.MaxRequestSize
+	if maxSize == 0 {
+		maxSize = DefaultMaxRequestSize
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize)
+
+	// Parse the request.
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, "invalid multipart request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Create a temporary directory and remove it on defer again.
+	tmpDir, err := ioutil.TempDir(h.Options.TempDir, "fillpdfhttp-")
+	if err != nil {
+		h.serverError(w, fmt.Errorf("failed to create temporary directory: %w", err))
+		return
+	}
+	defer os.RemoveAll(tmpDir)
+
+	formPDFFile := filepath.Join(tmpDir, "form.pdf")
+	destPDFFile := filepath.Join(tmpDir, "filled.pdf")
+
+	var (
+		form        fillpdf.Form
+		hasTemplate bool
+		opts        = h.Options
+	)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			http.Error(w, "invalid multipart request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		switch part.FormName() {
+		case "template":
+			err = writeFile(formPDFFile, part)
+			hasTemplate = true
+		case "data":
+			form, err = fillpdf.FormFromJSON(part)
+		case "options":
+			err = decodeOptions(part, &opts)
+		}
+		part.Close()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid %s part: %v", part.FormName(), err), http.StatusBadRequest)
+			return
+		}
+	}
+	if !hasTemplate {
+		http.Error(w, "missing template part", http.StatusBadRequest)
+		return
+	}
+
+	// Fill the form.
+	opts.Overwrite = true
+	err = fillpdf.FillContext(r.Context(), form, formPDFFile, destPDFFile, opts)
+	if err != nil {
+		h.fillError(w, err)
+		return
+	}
+
+	// Send the filled PDF.
+	f, err := os.Open(destPDFFile)
+	if err != nil {
+		h.serverError(w, err)
+		return
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		h.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Length", strconv.FormatInt(fi.Size(), 10))
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, f)
+}
+
+// fillError responds with the error of the fill. Errors caused by the
+// request are reported to the client, all other errors are only logged
+// as they may contain temporary paths or the output of the utilities.
+func (h *Handler) fillError(w http.ResponseWriter, err error) {
+	var (
+		unknownErr *fillpdf.UnknownFieldsError
+		missingErr *fillpdf.MissingFieldsError
+		maxLenErr  *fillpdf.MaxLenError
+		radioErr   *fillpdf.RadioValueError
+		verifyErr  *fillpdf.VerifyError
+	)
+	switch {
+	case isPasswordError(err):
+		log.Printf("fillpdfhttp: %v", err)
+		http.Error(w, "wrong input password", http.StatusBadRequest)
+	case errors.As(err, &unknownErr), errors.As(err, &missingErr), errors.As(err, &maxLenErr),
+		errors.As(err, &radioErr), errors.As(err, &verifyErr):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		h.serverError(w, err)
+	}
+}
+
+// serverError logs the error and responds without its details.
+func (h *Handler) serverError(w http.ResponseWriter, err error) {
+	log.Printf("fillpdfhttp: %v", err)
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+// isPasswordError returns whether the form PDF could not be opened
+// with the input password.
+func isPasswordError(err error) bool {
+	if errors.Is(err, pdfcpu.ErrWrongPassword) {
+		return true
+	}
+
+	// pdftk reports a missing or wrong password on stderr.
+	var execErr *fillpdf.ExecError
+	return errors.As(err, &execErr) && strings.Contains(strings.ToUpper(execErr.Stderr), "PASSWORD")
+}
+
+// decodeOptions decodes the JSON request options into opts.
+func decodeOptions(r io.Reader, opts *fillpdf.Options) error {
+	var ro requestOptions
+	err := json.NewDecoder(r).Decode(&ro)
+	if err != nil {
+		return err
+	}
+
+	if ro.Flatten != nil {
+		opts.Flatten = *ro.Flatten
+	}
+	if ro.NeedAppearances != nil {
+		opts.NeedAppearances = *ro.NeedAppearances
+	}
+	if ro.DropXFA != nil {
+		opts.DropXFA = *ro.DropXFA
+	}
+	if ro.InputPassword != nil {
+		opts.InputPassword = *ro.InputPassword
+	}
+	if ro.Encoding != nil {
+		switch *ro.Encoding {
+		case "", "utf16":
+			opts.Encoding = fillpdf.EncodingUTF16
+		case "latin1":
+			opts.Encoding = fillpdf.EncodingLatin1
+		default:
+			return fmt.Errorf("invalid encoding: %s", *ro.Encoding)
+		}
+	}
+	return nil
+}
+
+// writeFile creates the file named path and writes the contents read from r to it.
+func writeFile(path string, r io.Reader) (err error) {
+	out, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return
+	}
+	defer func() {
+		cerr := out.Close()
+		if err == nil {
+			err = cerr
+		}
+	}()
+	_, err = io.Copy(out, r)
+	return
+}
@@ -0,0 +1,117 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+// Field flag bits as defined by the PDF specification.
+// Some bits have a different meaning depending on the field type.
+const (
+	flagReadOnly          = 1 << 0
+	flagRequired          = 1 << 1
+	flagNoExport          = 1 << 2
+	flagMultiline         = 1 << 12
+	flagPassword          = 1 << 13
+	flagNoToggleToOff     = 1 << 14
+	flagRadio             = 1 << 15
+	flagPushbutton        = 1 << 16
+	flagCombo             = 1 << 17
+	flagEdit              = 1 << 18
+	flagSort              = 1 << 19
+	flagFileSelect        = 1 << 20
+	flagMultiSelect       = 1 << 21
+	flagDoNotSpellCheck   = 1 << 22
+	flagDoNotScroll       = 1 << 23
+	flagComb              = 1 << 24
+	flagRichText          = 1 << 25
+	flagRadiosInUnison    = 1 << 25
+	flagCommitOnSelChange = 1 << 26
+)
+
+// FieldFlags are the decoded flags of a form field.
+// Flags which do not apply to the field type are always false.
+type FieldFlags struct {
+	// Raw is the undecoded flags bitmask.
+	Raw int
+
+	// All field types.
+	ReadOnly bool
+	Required bool
+	NoExport bool
+
+	// Text fields.
+	Multiline   bool
+	Password    bool
+	FileSelect  bool
+	DoNotScroll bool
+	Comb        bool
+	RichText    bool
+
+	// Text and choice fields.
+	DoNotSpellCheck bool
+
+	// Button fields.
+	NoToggleToOff  bool
+	Radio          bool
+	Pushbutton     bool
+	RadiosInUnison bool
+
+	// Choice fields.
+	Combo             bool
+	Edit              bool
+	Sort              bool
+	MultiSelect       bool
+	CommitOnSelChange bool
+}
+
+// decodeFieldFlags decodes the flags bitmask of a field with the given type.
+func decodeFieldFlags(fieldType string, raw int) FieldFlags {
+	has := func(bit int) bool {
+		return raw&bit != 0
+	}
+
+	f := FieldFlags{
+		Raw:      raw,
+		ReadOnly: has(flagReadOnly),
+		Required: has(flagRequired),
+		NoExport: has(flagNoExport),
+	}
+
+	switch fieldType {
+	case fieldTypeText:
+		f.Multiline = has(flagMultiline)
+		f.Password = has(flagPassword)
+		f.FileSelect = has(flagFileSelect)
+		f.DoNotSpellCheck = has(flagDoNotSpellCheck)
+		f.DoNotScroll = has(flagDoNotScroll)
+		f.Comb = has(flagComb)
+		f.RichText = has(flagRichText)
+	case fieldTypeButton:
+		f.NoToggleToOff = has(flagNoToggleToOff)
+		f.Radio = has(flagRadio)
+		f.Pushbutton = has(flagPushbutton)
+		f.RadiosInUnison = has(flagRadiosInUnison)
+	case fieldTypeChoice:
+		f.Combo = has(flagCombo)
+		f.Edit = has(flagEdit)
+		f.Sort = has(flagSort)
+		f.MultiSelect = has(flagMultiSelect)
+		f.DoNotSpellCheck = has(flagDoNotSpellCheck)
+		f.CommitOnSelChange = has(flagCommitOnSelChange)
+	}
+	return f
+}
@@ -0,0 +1,366 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/pdfcpu/pdfcpu/pkg/font"
+	pdffont "github.com/pdfcpu/pdfcpu/pkg/pdfcpu/font"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"golang.org/x/text/encoding/charmap"
+)
+
+var (
+	// userFonts caches the PostScript names of the installed font files.
+	userFontsMutex sync.Mutex
+	userFonts      = make(map[string]string)
+)
+
+// fontValues returns the text values with characters missing from the
+// WinAnsi encoding, which can not be shown with the template fonts.
+func fontValues(form Form) map[string]string {
+	values := make(map[string]string)
+	for key, value := range form {
+		s, ok := value.(string)
+		if ok && needsFont(s) {
+			values[key] = s
+		}
+	}
+	return values
+}
+
+// needsFont returns whether the value contains characters which
+// can not be shown with the WinAnsi encoding of simple fonts.
+func needsFont(value string) bool {
+	for _, r := range value {
+		if _, ok := charmap.Windows1252.EncodeRune(r); !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// fontAppearancesPass returns a pass replacing the appearances of the
+// fields with the values by appearances shown with the font files.
+// The used glyphs of the fonts are embedded.
+func fontAppearancesPass(values map[string]string, files []string) pass {
+	return func(ctx context.Context, tmpDir, inputFile, outputFile string) error {
+		err := ctx.Err()
+		if err != nil {
+			return err
+		}
+
+		fonts, err := installFonts(tmpDir, files)
+		if err != nil {
+			return err
+		}
+
+		err = setFontAppearances(inputFile, outputFile, values, fonts)
+		if err != nil {
+			return fmt.Errorf("failed to create the field appearances: %w", err)
+		}
+		return nil
+	}
+}
+
+// installFonts installs the TrueType font files as pdfcpu user fonts
+// and returns their PostScript names. Installed fonts are cached.
+func installFonts(tmpDir string, files []string) ([]string, error) {
+	userFontsMutex.Lock()
+	defer userFontsMutex.Unlock()
+
+	// Ensure the pdfcpu user font directory exists.
+	_ = pdfcpuConfig("")
+
+	names := make([]string, len(files))
+	for i, file := range files {
+		path, err := filepath.Abs(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the absolute path: %w", err)
+		}
+		if name, ok := userFonts[path]; ok {
+			names[i] = name
+			continue
+		}
+
+		name, err := installFont(tmpDir, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to install font '%s': %w", file, err)
+		}
+		userFonts[path] = name
+		names[i] = name
+	}
+	return names, nil
+}
+
+// installFont installs the TrueType font file into the pdfcpu user font
+// directory. The font is installed into a scratch directory first to
+// obtain its PostScript name, which names the installed font.
+func installFont(tmpDir, path string) (string, error) {
+	dir, err := os.MkdirTemp(tmpDir, "font")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	err = font.InstallTrueTypeFont(dir, path)
+	if err != nil {
+		return "", err
+	}
+
+	matches, err := filepath.Glob(filepath.Join(dir, "*.gob"))
+	if err != nil {
+		return "", err
+	} else if len(matches) != 1 {
+		return "", fmt.Errorf("unsupported font file")
+	}
+	name := strings.TrimSuffix(filepath.Base(matches[0]), ".gob")
+
+	err = copyFile(matches[0], filepath.Join(font.UserFontDir, filepath.Base(matches[0])))
+	if err != nil {
+		return "", err
+	}
+	return name, font.LoadUserFonts()
+}
+
+// userFont returns the metrics of the installed user font.
+func userFont(name string) font.TTFLight {
+	font.UserFontMetricsLock.RLock()
+	defer font.UserFontMetricsLock.RUnlock()
+	return font.UserFontMetrics[name]
+}
+
+// fontFor returns the first font containing all characters of the value.
+func fontFor(value string, fonts []string) (string, bool) {
+Fonts:
+	for _, name := range fonts {
+		ttf := userFont(name)
+		for _, r := range value {
+			if _, ok := ttf.Chars[uint32(r)]; !ok && !unicode.IsControl(r) {
+				continue Fonts
+			}
+		}
+		return name, true
+	}
+	return "", false
+}
+
+// glyphs returns the glyph indices of the characters of the value.
+// Control characters have no glyph and are skipped.
+func glyphs(ttf font.TTFLight, value string) []uint16 {
+	gids := make([]uint16, 0, len(value))
+	for _, r := range value {
+		if gid, ok := ttf.Chars[uint32(r)]; ok {
+			gids = append(gids, gid)
+		}
+	}
+	return gids
+}
+
+// fontField is a field whose appearance is shown with a user font.
+type fontField struct {
+	d     types.Dict
+	value string
+	font  string
+}
+
+func setFontAppearances(inputFile, outputFile string, values map[string]string, fonts []string) error {
+	ctx, err := readPdfcpuContext(inputFile, "")
+	if err != nil {
+		return err
+	}
+
+	form, err := acroForm(ctx)
+	if err != nil {
+		return err
+	} else if form == nil {
+		return fmt.Errorf("document has no form")
+	}
+
+	// Collect the fields first, because the font subsets
+	// are created with the glyphs of all values.
+	var fields []fontField
+	used := make(map[string]map[uint16]bool)
+	err = walkFields(ctx, func(name string, d types.Dict) error {
+		value, ok := values[name]
+		if !ok {
+			return nil
+		}
+		fontName, ok := fontFor(value, fonts)
+		if !ok {
+			return fmt.Errorf("no font contains all characters of field '%s'", name)
+		}
+
+		if used[fontName] == nil {
+			used[fontName] = make(map[uint16]bool)
+		}
+		for _, gid := range glyphs(userFont(fontName), value) {
+			used[fontName][gid] = true
+		}
+		fields = append(fields, fontField{d: d, value: value, font: fontName})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// pdfcpu embeds the subset of the used glyphs as composite font.
+	if ctx.UsedGIDs == nil {
+		ctx.UsedGIDs = make(map[string]map[uint16]bool)
+	}
+	refs := make(map[string]types.IndirectRef, len(used))
+	for fontName, gids := range used {
+		ctx.UsedGIDs[fontName] = gids
+		ref, err := pdffont.EnsureFontDict(ctx.XRefTable, fontName, "", "", false, nil)
+		if err != nil {
+			return err
+		}
+		refs[fontName] = *ref
+	}
+
+	formDA := form.StringEntry("DA")
+	formQ, err := intEntry(ctx, form, "Q")
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		da := formDA
+		if s := f.d.StringEntry("DA"); s != nil {
+			da = s
+		}
+		var a defaultAppearance
+		if da != nil {
+			a = parseDefaultAppearance(*da)
+		}
+		q := formQ
+		if _, ok := f.d.Find("Q"); ok {
+			q, err = intEntry(ctx, f.d, "Q")
+			if err != nil {
+				return err
+			}
+		}
+
+		widgets, err := fieldWidgets(ctx, f.d)
+		if err != nil {
+			return err
+		}
+		for _, w := range widgets {
+			rectArr, err := ctx.DereferenceArray(w["Rect"])
+			if err != nil {
+				return err
+			} else if len(rectArr) != 4 {
+				continue
+			}
+			rect, err := ctx.RectForArray(rectArr)
+			if err != nil {
+				return err
+			}
+
+			ap, err := fontAppearance(ctx, rect.Width(), rect.Height(), a, q, f.font, refs[f.font], f.value)
+			if err != nil {
+				return err
+			}
+			w["AP"] = types.Dict{"N": ap}
+		}
+	}
+
+	// Viewers would otherwise regenerate the appearances with the template fonts.
+	form.Delete("NeedAppearances")
+
+	return writePdfcpuContext(ctx, outputFile)
+}
+
+// fieldWidgets returns the widget annotations of the field dictionary.
+// A field with a single widget is merged with its widget annotation.
+func fieldWidgets(ctx *model.Context, d types.Dict) ([]types.Dict, error) {
+	if _, ok := d.Find("Rect"); ok {
+		return []types.Dict{d}, nil
+	}
+
+	kids, err := ctx.DereferenceArray(d["Kids"])
+	if err != nil {
+		return nil, err
+	}
+	var widgets []types.Dict
+	for _, k := range kids {
+		kd, err := ctx.DereferenceDict(k)
+		if err != nil {
+			return nil, err
+		} else if kd != nil && kd["T"] == nil {
+			widgets = append(widgets, kd)
+		}
+	}
+	return widgets, nil
+}
+
+// fontAppearance adds a single line appearance stream of the value
+// shown with the user font to the document. A font size of zero in
+// the default appearance fits the value into the widget.
+func fontAppearance(ctx *model.Context, w, h float64, a defaultAppearance, q int, fontName string, ref types.IndirectRef, value string) (types.IndirectRef, error) {
+	const padding = 2
+
+	ttf := userFont(fontName)
+	gids := glyphs(ttf, value)
+
+	var width int
+	var hex strings.Builder
+	for _, gid := range gids {
+		width += ttf.GlyphWidths[gid]
+		fmt.Fprintf(&hex, "%04X", gid)
+	}
+
+	size, _ := strconv.ParseFloat(a.size, 64)
+	if size <= 0 {
+		size = min(12, (h-2*padding)*0.7)
+		if width > 0 {
+			size = min(size, (w-2*padding)*1000/float64(width))
+		}
+	}
+
+	textWidth := float64(width) * size / 1000
+	x := float64(padding)
+	switch q {
+	case 1:
+		x = (w - textWidth) / 2
+	case 2:
+		x = w - padding - textWidth
+	}
+	textHeight := float64(ttf.Ascent-ttf.Descent) * size / 1000
+	y := (h-textHeight)/2 - float64(ttf.Descent)*size/1000
+
+	colorOp := "0 g"
+	if len(a.color) > 0 {
+		colorOp = strings.Join(a.color, " ")
+	}
+
+	content := fmt.Sprintf("/Tx BMC\nq\n1 1 %.2f %.2f re W n\nBT\n/%s %.2f Tf\n%s\n%.2f %.2f Td\n<%s> Tj\nET\nQ\nEMC\n",
+		w-2, h-2, fontName, size, colorOp, x, y, hex.String())
+
+	return newFormXObject(ctx, w, h, content, types.Dict{"Font": types.Dict{fontName: ref}})
+}
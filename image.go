@@ -0,0 +1,220 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"os"
+
+	// Register the supported image formats.
+	_ "image/jpeg"
+	_ "image/png"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// Image is a form value placing a PNG or JPEG image into the rectangle
+// of a form field, typically an image push button.
+// The image is scaled to fit and centered within the rectangle.
+// It is stamped onto the page after filling, therefore the form should
+// be flattened to prevent the field's appearance from covering the image.
+type Image struct {
+	// Data is the PNG or JPEG encoded image.
+	Data []byte
+}
+
+// String returns an empty string. Images have no text value.
+func (Image) String() string {
+	return ""
+}
+
+// SignatureImage is a form value placing a PNG or JPEG image of a
+// handwritten signature into a signature field. It behaves like Image,
+// but fails if the field is not a signature field.
+// Use GetSignatureFields to detect the signature fields of a form.
+type SignatureImage Image
+
+// String returns an empty string. Images have no text value.
+func (SignatureImage) String() string {
+	return ""
+}
+
+// imageEncoder is implemented by form values rendered as image, e.g. QRCode.
+// The image is fitted into the rectangle.
+type imageEncoder interface {
+	encodeImage(rect Rect) ([]byte, error)
+}
+
+// imageValue is an image form value.
+type imageValue struct {
+	Image
+	// Signature requires the field to be a signature field.
+	Signature bool
+	// Encoder renders the image data if set.
+	Encoder imageEncoder
+}
+
+// data returns the image data, which is rendered by the encoder
+// for the rectangle if set.
+func (v imageValue) data(rect Rect) ([]byte, error) {
+	if v.Encoder != nil {
+		return v.Encoder.encodeImage(rect)
+	}
+	return v.Data, nil
+}
+
+// imageStamp is an image stamped onto a page.
+type imageStamp struct {
+	Page     int
+	Rect     Rect
+	Viewport Rect
+	Data     []byte
+}
+
+// splitImages returns a copy of the form without the image values
+// and the image values separately.
+func splitImages(form Form) (Form, map[string]imageValue) {
+	images := make(map[string]imageValue)
+	rest := make(Form, len(form))
+	for key, value := range form {
+		switch v := value.(type) {
+		case Image:
+			images[key] = imageValue{Image: v}
+		case SignatureImage:
+			images[key] = imageValue{Image: Image(v), Signature: true}
+		case imageEncoder:
+			images[key] = imageValue{Encoder: v}
+		default:
+			rest[key] = value
+		}
+	}
+	return rest, images
+}
+
+// imageStamps places the images into the widget rectangles of their fields.
+func imageStamps(images map[string]imageValue, widgets []widget) ([]imageStamp, error) {
+	var stamps []imageStamp
+	for name, img := range images {
+		found := false
+		for _, w := range widgets {
+			if w.Name != name {
+				continue
+			} else if img.Signature && w.Type != pdfFieldTypeSignature {
+				return nil, fmt.Errorf("field is not a signature field: '%s'", name)
+			}
+			data, err := img.data(w.Rect)
+			if err != nil {
+				return nil, fmt.Errorf("field '%s': %w", name, err)
+			}
+			stamps = append(stamps, imageStamp{
+				Page:     w.Page,
+				Rect:     w.Rect,
+				Viewport: w.Viewport,
+				Data:     data,
+			})
+			found = true
+		}
+		if !found {
+			return nil, fmt.Errorf("image field does not exist: '%s'", name)
+		}
+	}
+	return stamps, nil
+}
+
+// fixedStamp returns the stamp of the image rendered by the encoder
+// placed at fixed page coordinates. The viewports are the visible
+// regions of the pages.
+func fixedStamp(e imageEncoder, page int, rect Rect, viewports []Rect) (imageStamp, error) {
+	if page < 1 || page > len(viewports) {
+		return imageStamp{}, fmt.Errorf("invalid page number %d", page)
+	}
+	data, err := e.encodeImage(rect)
+	if err != nil {
+		return imageStamp{}, err
+	}
+	return imageStamp{
+		Page:     page,
+		Rect:     rect,
+		Viewport: viewports[page-1],
+		Data:     data,
+	}, nil
+}
+
+// stampImagesPass returns a pass stamping the images onto their pages.
+func stampImagesPass(stamps []imageStamp) pass {
+	return func(ctx context.Context, tmpDir, inputFile, outputFile string) error {
+		err := stampImages(inputFile, outputFile, stamps)
+		if err != nil {
+			return fmt.Errorf("failed to stamp images: %w", err)
+		}
+		return nil
+	}
+}
+
+func stampImages(inputFile, outputFile string, stamps []imageStamp) error {
+	m := make(map[int][]*model.Watermark)
+	for _, s := range stamps {
+		wm, err := imageWatermark(s)
+		if err != nil {
+			return err
+		}
+		m[s.Page] = append(m[s.Page], wm)
+	}
+
+	in, err := os.Open(inputFile)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	var out bytes.Buffer
+	err = api.AddWatermarksSliceMap(in, &out, m, pdfcpuReadConfig(""))
+	if err != nil {
+		return err
+	}
+
+	return writeFile(outputFile, &out)
+}
+
+// imageWatermark returns a pdfcpu stamp fitting the image into its rectangle.
+func imageWatermark(s imageStamp) (*model.Watermark, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(s.Data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid image: %w", err)
+	} else if cfg.Width == 0 || cfg.Height == 0 {
+		return nil, fmt.Errorf("invalid image: empty image")
+	}
+
+	// Scale to fit and center within the rectangle.
+	w, h := float64(cfg.Width), float64(cfg.Height)
+	scale := s.Rect.Width() / w
+	if sh := s.Rect.Height() / h; sh < scale {
+		scale = sh
+	}
+	dx := s.Rect.LLX - s.Viewport.LLX + (s.Rect.Width()-w*scale)/2
+	dy := s.Rect.LLY - s.Viewport.LLY + (s.Rect.Height()-h*scale)/2
+
+	desc := fmt.Sprintf("position:bl, offset:%.2f %.2f, scalefactor:%.6f abs, rotation:0", dx, dy, scale)
+	return api.ImageWatermarkForReader(bytes.NewReader(s.Data), desc, true, false, types.POINTS)
+}
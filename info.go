@@ -0,0 +1,318 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Info is the document information of a PDF.
+type Info struct {
+	// Version is the PDF version from the file header, e.g. "1.7".
+	Version string
+	// Entries are the entries of the document info dictionary,
+	// e.g. Title, Author and Producer.
+	Entries map[string]string
+	// NumberOfPages is the page count.
+	NumberOfPages int
+	// Pages are the page dimensions in page order.
+	Pages []Page
+	// Bookmarks is the document outline.
+	Bookmarks []Bookmark
+}
+
+// Page describes the geometry of a page.
+type Page struct {
+	// Number is the page number starting with 1.
+	Number int
+	// Rotation is the page rotation in degrees: 0, 90, 180 or 270.
+	Rotation int
+	// MediaBox is the page media box.
+	MediaBox Rect
+	// CropBox is the visible page region. It equals the media box
+	// if the page has no crop box.
+	CropBox Rect
+}
+
+// GetInfo returns the document information of the PDF file.
+// The InputPassword option is used for password protected PDF files and
+// TempDir for the intermediate files, the other options are ignored.
+// The pdftk utility is required.
+func GetInfo(pdfFile string, options ...Options) (*Info, error) {
+	return GetInfoContext(context.Background(), pdfFile, options...)
+}
+
+// GetInfoContext is like GetInfo, but the context is used to cancel
+// the spawned external processes.
+func GetInfoContext(ctx context.Context, pdfFile string, options ...Options) (*Info, error) {
+	opts := getOptions(options)
+	ctx = opts.instrument(ctx)
+
+	inputs, err := absExistingFiles([]string{pdfFile})
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkPdftk()
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a temporary directory and remove it on defer again.
+	tmpDir, err := createTempDir(opts.TempDir)
+	if err != nil {
+		return nil, err
+	}
+	defer removeTempDir(tmpDir)
+
+	data, err := dumpData(ctx, tmpDir, inputs[0], opts.InputPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := parseInfo(data)
+	if err != nil {
+		return nil, err
+	}
+
+	info.Version, err = readPDFVersion(inputs[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF version: %w", err)
+	}
+	return info, nil
+}
+
+// PageInfo returns the geometry of all pages of the PDF file in page order.
+// The page count is the length of the returned slice.
+// The InputPassword option is used for password protected PDF files and
+// TempDir for the intermediate files, the other options are ignored.
+// The pdftk utility is required.
+func PageInfo(pdfFile string, options ...Options) ([]Page, error) {
+	return PageInfoContext(context.Background(), pdfFile, options...)
+}
+
+// PageInfoContext is like PageInfo, but the context is used to cancel
+// the spawned external processes.
+func PageInfoContext(ctx context.Context, pdfFile string, options ...Options) ([]Page, error) {
+	info, err := GetInfoContext(ctx, pdfFile, options...)
+	if err != nil {
+		return nil, err
+	}
+	return info.Pages, nil
+}
+
+// dumpData returns the output of pdftk's dump_data_utf8 for the PDF file.
+func dumpData(ctx context.Context, dir, pdfFile, password string) ([]byte, error) {
+	args := append(pdftkInput(pdfFile, password), "dump_data_utf8")
+	out, err := runPdftkOutput(ctx, dir, args...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// parseInfo parses the output of pdftk's dump_data.
+// Records start with a Begin line followed by key value pairs.
+func parseInfo(data []byte) (*Info, error) {
+	info := &Info{Entries: make(map[string]string)}
+
+	var (
+		infoKey   string
+		page      *Page
+		bookmark  *flatBookmark
+		bookmarks []flatBookmark
+	)
+
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		line := s.Text()
+		if line == "PageMediaBegin" {
+			info.Pages = append(info.Pages, Page{})
+			page = &info.Pages[len(info.Pages)-1]
+			continue
+		} else if line == "BookmarkBegin" {
+			bookmarks = append(bookmarks, flatBookmark{})
+			bookmark = &bookmarks[len(bookmarks)-1]
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			continue
+		}
+
+		var err error
+		switch key {
+		case "InfoKey":
+			infoKey = value
+		case "InfoValue":
+			info.Entries[infoKey] = value
+		case "NumberOfPages":
+			info.NumberOfPages, err = strconv.Atoi(value)
+		case "PageMediaNumber":
+			if page != nil {
+				page.Number, err = strconv.Atoi(value)
+			}
+		case "PageMediaRotation":
+			if page != nil {
+				page.Rotation, err = strconv.Atoi(value)
+			}
+		case "PageMediaRect":
+			if page != nil {
+				page.MediaBox, err = parseRect(value)
+				page.CropBox = page.MediaBox
+			}
+		case "PageMediaCropRect":
+			if page != nil {
+				page.CropBox, err = parseRect(value)
+			}
+		case "BookmarkTitle":
+			if bookmark != nil {
+				bookmark.Title = value
+			}
+		case "BookmarkLevel":
+			if bookmark != nil {
+				bookmark.Level, err = strconv.Atoi(value)
+			}
+		case "BookmarkPageNumber":
+			if bookmark != nil {
+				bookmark.Page, err = strconv.Atoi(value)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value '%s': %w", key, value, err)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	info.Bookmarks = bookmarkTree(bookmarks)
+	return info, nil
+}
+
+// parseRect parses a rectangle of the form "llx lly urx ury".
+func parseRect(s string) (r Rect, err error) {
+	f := strings.Fields(s)
+	if len(f) != 4 {
+		return r, fmt.Errorf("expected 4 coordinates")
+	}
+
+	var v [4]float64
+	for i := range f {
+		v[i], err = strconv.ParseFloat(f[i], 64)
+		if err != nil {
+			return
+		}
+	}
+	return Rect{LLX: v[0], LLY: v[1], URX: v[2], URY: v[3]}, nil
+}
+
+// readPDFVersion reads the PDF version from the file header.
+func readPDFVersion(pdfFile string) (string, error) {
+	file, err := os.Open(pdfFile)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	// The header may be preceded by garbage within the first 1024 bytes.
+	buf := make([]byte, 1024)
+	n, err := io.ReadFull(file, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	buf = buf[:n]
+
+	i := bytes.Index(buf, []byte("%PDF-"))
+	if i < 0 {
+		return "", fmt.Errorf("missing PDF header")
+	}
+	v := buf[i+5:]
+	end := 0
+	for end < len(v) && (v[end] == '.' || (v[end] >= '0' && v[end] <= '9')) {
+		end++
+	}
+	return string(v[:end]), nil
+}
+
+var lineBreakReplacer = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ")
+
+// docInfoPass returns a pass setting the document info entries with pdftk.
+func docInfoPass(info map[string]string) pass {
+	return func(ctx context.Context, tmpDir, inputFile, outputFile string) error {
+		err := checkPdftk()
+		if err != nil {
+			return err
+		}
+
+		infoFile := filepath.Join(tmpDir, "info.txt")
+		err = writeInfoFile(infoFile, info)
+		if err != nil {
+			return fmt.Errorf("failed to write document info file: %w", err)
+		}
+
+		err = runPdftk(ctx, tmpDir, inputFile, "update_info_utf8", infoFile, "output", outputFile)
+		if err != nil {
+			return fmt.Errorf("failed to update document info: %w", err)
+		}
+		return nil
+	}
+}
+
+// writeInfoFile writes the document info entries in the pdftk dump_data format.
+// Line breaks within values are replaced by spaces, as the format is line based.
+func writeInfoFile(path string, info map[string]string) (err error) {
+	file, err := createFile(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		cerr := file.Close()
+		if err == nil {
+			err = cerr
+		}
+	}()
+
+	keys := make([]string, 0, len(info))
+	for key := range info {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	w := bufio.NewWriter(file)
+	for _, key := range keys {
+		fmt.Fprintf(w, "InfoBegin\nInfoKey: %s\nInfoValue: %s\n", singleLine(key), singleLine(info[key]))
+	}
+	return w.Flush()
+}
+
+// singleLine replaces line breaks with spaces.
+func singleLine(s string) string {
+	return lineBreakReplacer.Replace(s)
+}
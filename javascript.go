@@ -0,0 +1,285 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// RemoveJavaScript removes the document level JavaScript and all JavaScript
+// actions of the document, pages, annotations and form fields of the input
+// PDF file and writes the result to the destination file. An existing
+// destination file is replaced. No external utility is required.
+// Scripts embedded into XFA forms are not removed, use DropXFA instead.
+func RemoveJavaScript(inputPDFFile, destPDFFile string) error {
+	return RemoveJavaScriptContext(context.Background(), inputPDFFile, destPDFFile)
+}
+
+// RemoveJavaScriptContext is like RemoveJavaScript, but checks the context
+// for cancellation before processing.
+func RemoveJavaScriptContext(ctx context.Context, inputPDFFile, destPDFFile string) (err error) {
+	// Get the absolute paths.
+	destPDFFile, err = filepath.Abs(destPDFFile)
+	if err != nil {
+		return fmt.Errorf("failed to create the absolute path: %w", err)
+	}
+	inputs, err := absExistingFiles([]string{inputPDFFile})
+	if err != nil {
+		return err
+	}
+
+	// Create a temporary directory and remove it on defer again.
+	tmpDir, err := createTempDir("")
+	if err != nil {
+		return err
+	}
+	defer removeTempDir(tmpDir)
+
+	outputFile := filepath.Join(tmpDir, "output.pdf")
+	err = removeJavaScriptPass(ctx, tmpDir, inputs[0], outputFile)
+	if err != nil {
+		return err
+	}
+
+	return writeDestFile(outputFile, destPDFFile, true)
+}
+
+func removeJavaScriptPass(ctx context.Context, tmpDir, inputFile, outputFile string) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	err = removeJavaScript(inputFile, outputFile)
+	if err != nil {
+		return fmt.Errorf("failed to remove JavaScript: %w", err)
+	}
+	return nil
+}
+
+// scriptActions are the action types running JavaScript.
+var scriptActions = map[string]bool{"JavaScript": true}
+
+// removeJavaScript removes the JavaScript name tree of the document and
+// the JavaScript actions of all objects. Other actions are kept.
+func removeJavaScript(inputFile, outputFile string) error {
+	ctx, err := readPdfcpuContext(inputFile, "")
+	if err != nil {
+		return err
+	}
+
+	err = removeNameTree(ctx, "JavaScript")
+	if err != nil {
+		return err
+	}
+	err = removeActions(ctx, scriptActions)
+	if err != nil {
+		return err
+	}
+
+	return writePdfcpuContext(ctx, outputFile)
+}
+
+// removeNameTree removes the name tree of the document's name dictionary
+// with all objects referenced by it.
+func removeNameTree(ctx *model.Context, name string) error {
+	root, err := ctx.Catalog()
+	if err != nil {
+		return err
+	}
+	names, err := ctx.DereferenceDict(root["Names"])
+	if err != nil {
+		return err
+	} else if _, ok := names.Find(name); !ok {
+		return nil
+	}
+
+	// pdfcpu writes its cached name trees back.
+	delete(ctx.Names, name)
+	return ctx.RemoveNameTree(name)
+}
+
+// removeActions removes the actions of the action types from all objects.
+// Other actions are kept.
+func removeActions(ctx *model.Context, actions map[string]bool) error {
+	// Actions are referenced by the catalog, pages, annotations, fields
+	// and other actions, so all objects are visited.
+	var removed []int
+	for objNr, entry := range ctx.Table {
+		if entry == nil || entry.Free {
+			continue
+		}
+		var err error
+		switch o := entry.Object.(type) {
+		case types.Dict:
+			if s := o.NameEntry("S"); s != nil && actions[*s] {
+				removed = append(removed, objNr)
+				continue
+			}
+			err = stripActions(ctx, o, actions, 0)
+		case types.Array:
+			err = stripArrayActions(ctx, o, actions, 0)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// The unreferenced actions and their script streams would be written otherwise.
+	for _, objNr := range removed {
+		d, ok := ctx.Table[objNr].Object.(types.Dict)
+		if !ok {
+			continue
+		}
+		if ref, ok := d["JS"].(types.IndirectRef); ok {
+			err := ctx.FreeObject(ref.ObjectNumber.Value())
+			if err != nil {
+				return err
+			}
+		}
+		err := ctx.FreeObject(objNr)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stripActions removes the actions of the action types from the
+// dictionary and its direct objects. Indirect objects are visited on their own.
+func stripActions(ctx *model.Context, d types.Dict, actions map[string]bool, depth int) error {
+	// Limit the depth to protect against deeply nested objects.
+	if depth >= 32 {
+		return nil
+	}
+
+	for _, key := range []string{"A", "OpenAction"} {
+		ok, err := isAction(ctx, d[key], actions)
+		if err != nil {
+			return err
+		} else if ok {
+			d.Delete(key)
+		}
+	}
+
+	// Additional actions map triggers to actions.
+	aa, err := ctx.DereferenceDict(d["AA"])
+	if err != nil {
+		return err
+	}
+	for trigger, action := range aa {
+		ok, err := isAction(ctx, action, actions)
+		if err != nil {
+			return err
+		} else if ok {
+			aa.Delete(trigger)
+		}
+	}
+	if aa != nil && len(aa) == 0 {
+		d.Delete("AA")
+	}
+
+	// Actions are chained by their Next entry.
+	if s := d.NameEntry("S"); s != nil {
+		// Rendition actions may run a script, too.
+		if *s == "Rendition" && actions["JavaScript"] {
+			d.Delete("JS")
+		}
+		if next, ok := d["Next"].(types.Array); ok {
+			kept := types.Array{}
+			for _, action := range next {
+				ok, err := isAction(ctx, action, actions)
+				if err != nil {
+					return err
+				} else if !ok {
+					kept = append(kept, action)
+				}
+			}
+			if len(kept) > 0 {
+				d["Next"] = kept
+			} else {
+				d.Delete("Next")
+			}
+		} else {
+			ok, err := isAction(ctx, d["Next"], actions)
+			if err != nil {
+				return err
+			} else if ok {
+				d.Delete("Next")
+			}
+		}
+	}
+
+	for _, o := range d {
+		switch v := o.(type) {
+		case types.Dict:
+			err = stripActions(ctx, v, actions, depth+1)
+		case types.Array:
+			err = stripArrayActions(ctx, v, actions, depth+1)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func stripArrayActions(ctx *model.Context, a types.Array, actions map[string]bool, depth int) error {
+	for _, o := range a {
+		var err error
+		switch v := o.(type) {
+		case types.Dict:
+			err = stripActions(ctx, v, actions, depth+1)
+		case types.Array:
+			err = stripArrayActions(ctx, v, actions, depth+1)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isAction returns whether the object is an action of the action types.
+func isAction(ctx *model.Context, o types.Object, actions map[string]bool) (bool, error) {
+	if o == nil {
+		return false, nil
+	}
+	d, ok := o.(types.Dict)
+	if !ok {
+		if _, ok := o.(types.IndirectRef); !ok {
+			return false, nil
+		}
+		o, err := ctx.Dereference(o)
+		if err != nil {
+			return false, err
+		}
+		d, ok = o.(types.Dict)
+		if !ok {
+			return false, nil
+		}
+	}
+	s := d.NameEntry("S")
+	return s != nil && actions[*s], nil
+}
@@ -0,0 +1,93 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// FillFromJSON fills the PDF form with the values of the JSON object read from r.
+// Nested objects are flattened to dotted field names, e.g. {"a": {"b": 1}}
+// fills the field "a.b". Numbers keep their JSON representation, booleans
+// check or uncheck checkboxes and arrays of scalar values are passed as []string.
+// Null values are skipped.
+func FillFromJSON(r io.Reader, formPDFFile, destPDFFile string, options ...Options) error {
+	return FillFromJSONContext(context.Background(), r, formPDFFile, destPDFFile, options...)
+}
+
+// FillFromJSONContext is like FillFromJSON, but the context is used to cancel
+// the fill process and the spawned external processes.
+func FillFromJSONContext(ctx context.Context, r io.Reader, formPDFFile, destPDFFile string, options ...Options) error {
+	form, err := FormFromJSON(r)
+	if err != nil {
+		return err
+	}
+	return FillContext(ctx, form, formPDFFile, destPDFFile, options...)
+}
+
+// FormFromJSON decodes the JSON object read from r to form values
+// like FillFromJSON.
+func FormFromJSON(r io.Reader) (Form, error) {
+	var obj map[string]interface{}
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	err := dec.Decode(&obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode JSON form values: %w", err)
+	}
+
+	form := make(Form)
+	err = flattenJSON(form, "", obj)
+	if err != nil {
+		return nil, err
+	}
+	return form, nil
+}
+
+func flattenJSON(form Form, prefix string, obj map[string]interface{}) error {
+	for key, value := range obj {
+		name := prefix + key
+
+		switch v := value.(type) {
+		case nil:
+			continue
+		case map[string]interface{}:
+			err := flattenJSON(form, name+".", v)
+			if err != nil {
+				return err
+			}
+		case []interface{}:
+			values := make([]string, len(v))
+			for i, e := range v {
+				switch e.(type) {
+				case map[string]interface{}, []interface{}:
+					return fmt.Errorf("unsupported JSON value of field '%s': arrays must contain scalar values only", name)
+				}
+				values[i] = formatValue(e)
+			}
+			form[name] = values
+		default:
+			form[name] = v
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// libreOfficeNames are the binary names of LibreOffice searched
+// within the PATH in order.
+var libreOfficeNames = []string{"soffice", "libreoffice"}
+
+// LibreOfficeBackend fills the forms with pdfcpu and flattens them with
+// a headless LibreOffice. The filled PDF is imported into LibreOffice Draw
+// and exported again, so the field values are rendered by LibreOffice with
+// the fonts installed on the host. This handles some templates and fonts
+// which pdftk renders incorrectly.
+//
+// LibreOffice does not preserve form fields, therefore the forms are
+// filled by pdfcpu only if the Flatten option is disabled.
+type LibreOfficeBackend struct {
+	// Binary is the path of the soffice binary. Defaults to soffice
+	// and libreoffice within the PATH and the default install locations.
+	Binary string
+}
+
+// Fill fills the form PDF file and flattens it with LibreOffice.
+func (b *LibreOfficeBackend) Fill(ctx context.Context, tmpDir string, form Form, formPDFFile, outputFile string, opts Options) error {
+	if !opts.Flatten {
+		return Pdfcpu.Fill(ctx, tmpDir, form, formPDFFile, outputFile, opts)
+	}
+
+	bin, err := b.binary()
+	if err != nil {
+		return err
+	}
+
+	// Fill the form without locking the fields, which would be lost anyway.
+	fillOpts := opts
+	fillOpts.Flatten = false
+	filledFile := filepath.Join(tmpDir, "libreoffice-filled.pdf")
+	err = Pdfcpu.Fill(ctx, tmpDir, form, formPDFFile, filledFile, fillOpts)
+	if err != nil {
+		return err
+	}
+
+	// LibreOffice writes the exported file with the same name into the
+	// output directory. Each call uses its own profile, because concurrent
+	// instances can not share one.
+	outDir := filepath.Join(tmpDir, "libreoffice")
+	profileDir := filepath.Join(tmpDir, "libreoffice-profile")
+	err = runCommandInPath(ctx, tmpDir, bin,
+		"--headless",
+		"--norestore",
+		"--nolockcheck",
+		"-env:UserInstallation="+fileURL(profileDir),
+		"--infilter=draw_pdf_import",
+		"--convert-to", "pdf:draw_pdf_Export",
+		"--outdir", outDir,
+		filledFile,
+	)
+	if err != nil {
+		return fmt.Errorf("libreoffice error: %w", err)
+	}
+
+	// LibreOffice exits successfully even if the conversion failed.
+	exportedFile := filepath.Join(outDir, filepath.Base(filledFile))
+	e, err := exists(exportedFile)
+	if err != nil {
+		return err
+	} else if !e {
+		return fmt.Errorf("libreoffice error: failed to export the filled PDF")
+	}
+	return os.Rename(exportedFile, outputFile)
+}
+
+// binary returns the path of the LibreOffice binary.
+func (b *LibreOfficeBackend) binary() (string, error) {
+	if b.Binary != "" {
+		path, err := exec.LookPath(b.Binary)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrLibreOfficeNotFound, err)
+		}
+		return path, nil
+	}
+
+	for _, name := range libreOfficeNames {
+		path, err := exec.LookPath(name)
+		if err == nil {
+			return path, nil
+		}
+	}
+	return lookInstallPath(libreOfficeInstallPaths(), ErrLibreOfficeNotFound)
+}
+
+// fileURL returns the file URL of the absolute path.
+func fileURL(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		// Windows paths start with the drive letter.
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
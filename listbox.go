@@ -0,0 +1,74 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"fmt"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// multiValues returns the []string values of the form, which select
+// multiple options of list boxes.
+func multiValues(form Form) map[string][]string {
+	values := make(map[string][]string)
+	for key, value := range form {
+		if v, ok := value.([]string); ok {
+			values[key] = v
+		}
+	}
+	return values
+}
+
+// checkMultiValues checks that the fields of the []string values within
+// the form PDF file are list boxes and that multiple values are only
+// passed to list boxes with the MultiSelect flag. Unknown fields are skipped.
+func checkMultiValues(values map[string][]string, pdfFile, password string) error {
+	ctx, err := readPdfcpuContext(pdfFile, password)
+	if err != nil {
+		return err
+	}
+	return checkMultiValuesContext(ctx, values)
+}
+
+// checkMultiValuesContext is like checkMultiValues, but checks the
+// fields of the pdfcpu context.
+func checkMultiValuesContext(ctx *model.Context, values map[string][]string) error {
+	return walkFields(ctx, func(name string, d types.Dict) error {
+		v, ok := values[name]
+		if !ok {
+			return nil
+		}
+
+		_, typ, err := widgetField(ctx, d)
+		if err != nil {
+			return err
+		}
+		flags, err := inheritedFlags(ctx, d)
+		if err != nil {
+			return err
+		} else if typ != "Ch" || flags&flagCombo != 0 {
+			return fmt.Errorf("field is not a list box: '%s'", name)
+		} else if len(v) > 1 && flags&flagMultiSelect == 0 {
+			return fmt.Errorf("list box does not allow multiple selections: '%s'", name)
+		}
+		return nil
+	})
+}
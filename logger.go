@@ -0,0 +1,70 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"time"
+)
+
+type loggerKey struct{}
+
+// WithLogger returns a copy of the context carrying the logger.
+// The Context variants of all functions log the external commands they run
+// with it, including their durations and exit codes. Command arguments and
+// form values are never logged, as they may contain passwords and personal data.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// loggerFrom returns the logger of the context or nil.
+func loggerFrom(ctx context.Context) *slog.Logger {
+	l, _ := ctx.Value(loggerKey{}).(*slog.Logger)
+	return l
+}
+
+// withLogger returns the context carrying the logger of the options, if set.
+func (o Options) withLogger(ctx context.Context) context.Context {
+	if o.Logger == nil {
+		return ctx
+	}
+	return WithLogger(ctx, o.Logger)
+}
+
+// logCommand logs the finished external command with the logger of the context.
+func logCommand(ctx context.Context, name string, duration time.Duration, exitCode int, err error) {
+	l := loggerFrom(ctx)
+	if l == nil {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("command", filepath.Base(name)),
+		slog.Duration("duration", duration),
+		slog.Int("exitCode", exitCode),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+		l.LogAttrs(ctx, slog.LevelError, "fillpdf: command failed", attrs...)
+		return
+	}
+	l.LogAttrs(ctx, slog.LevelInfo, "fillpdf: command finished", attrs...)
+}
@@ -0,0 +1,137 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+const tagName = "fillpdf"
+
+var (
+	stringerType      = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	fieldValuerType   = reflect.TypeOf((*FieldValuer)(nil)).Elem()
+)
+
+// Marshal returns the form values of the struct v or a pointer to it.
+// Struct fields are mapped to PDF field names with the fillpdf struct tag:
+//
+//	Name    string   `fillpdf:"name"`
+//	Comment string   `fillpdf:"comment,omitempty"`
+//	Address Address  `fillpdf:"address"`
+//	Secret  string   `fillpdf:"-"`
+//
+// Fields of a tagged nested struct are prefixed with the tag name and a dot,
+// e.g. "address.street". Fields of untagged nested and embedded structs are
+// added without a prefix. Other untagged fields are ignored.
+// Pointers are dereferenced and nil pointers are skipped.
+// The omitempty option skips zero values.
+// Structs implementing fmt.Stringer, encoding.TextMarshaler or FieldValuer,
+// like time.Time, are treated as single values.
+func Marshal(v interface{}) (Form, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, fmt.Errorf("fillpdf: Marshal(nil %s)", rv.Type())
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("fillpdf: Marshal(non-struct %s)", rv.Type())
+	}
+
+	form := make(Form)
+	marshalStruct(form, "", rv)
+	return form, nil
+}
+
+// FillStruct marshals the struct v to form values with Marshal
+// and fills the PDF form with them.
+func FillStruct(v interface{}, formPDFFile, destPDFFile string, options ...Options) error {
+	return FillStructContext(context.Background(), v, formPDFFile, destPDFFile, options...)
+}
+
+// FillStructContext is like FillStruct, but the context is used to cancel
+// the fill process and the spawned external processes.
+func FillStructContext(ctx context.Context, v interface{}, formPDFFile, destPDFFile string, options ...Options) error {
+	form, err := Marshal(v)
+	if err != nil {
+		return err
+	}
+	return FillContext(ctx, form, formPDFFile, destPDFFile, options...)
+}
+
+func marshalStruct(form Form, prefix string, rv reflect.Value) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			// Unexported field.
+			continue
+		}
+
+		tag, hasTag := sf.Tag.Lookup(tagName)
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		omitEmpty := opts == "omitempty"
+
+		fv := rv.Field(i)
+		for fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				break
+			}
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Ptr || (omitEmpty && fv.IsZero()) {
+			continue
+		}
+
+		// Walk nested structs.
+		if fv.Kind() == reflect.Struct && !isValueStruct(fv.Type()) {
+			if name == "" {
+				marshalStruct(form, prefix, fv)
+			} else {
+				marshalStruct(form, prefix+name+".", fv)
+			}
+			continue
+		}
+
+		if !hasTag || name == "" || !fv.CanInterface() {
+			continue
+		}
+		form[prefix+name] = fv.Interface()
+	}
+}
+
+// isValueStruct returns whether the struct type represents a single value.
+func isValueStruct(t reflect.Type) bool {
+	for _, i := range []reflect.Type{stringerType, textMarshalerType, fieldValuerType} {
+		if t.Implements(i) || reflect.PtrTo(t).Implements(i) {
+			return true
+		}
+	}
+	return false
+}
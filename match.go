@@ -0,0 +1,138 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// FieldMatching defines how form keys are matched to field names.
+type FieldMatching int
+
+const (
+	// MatchExact requires the form keys to equal the field names.
+	// This is the default.
+	MatchExact FieldMatching = iota
+	// MatchCaseInsensitive ignores the case of the names.
+	MatchCaseInsensitive
+	// MatchNormalized ignores the case, whitespace, underscores and hyphens,
+	// e.g. "first_name" matches the field "FirstName".
+	MatchNormalized
+)
+
+// normalize returns the name in the form compared by the matching mode.
+func (m FieldMatching) normalize(name string) string {
+	switch m {
+	case MatchCaseInsensitive:
+		return strings.ToLower(name)
+	case MatchNormalized:
+		return strings.Map(func(r rune) rune {
+			if unicode.IsSpace(r) || r == '_' || r == '-' {
+				return -1
+			}
+			return unicode.ToLower(r)
+		}, name)
+	default:
+		return name
+	}
+}
+
+// matchFieldNames returns a copy of the form with the keys replaced by the
+// names of their matching fields. Exact matches take precedence.
+// Keys without a matching field are kept. An error is returned if a key
+// matches multiple fields or multiple keys match the same field.
+func matchFieldNames(form Form, fields []Field, m FieldMatching) (Form, error) {
+	if m == MatchExact {
+		return form, nil
+	}
+
+	exact := make(map[string]bool, len(fields))
+	normalized := make(map[string][]string, len(fields))
+	for _, f := range fields {
+		exact[f.Name] = true
+		n := m.normalize(f.Name)
+		normalized[n] = append(normalized[n], f.Name)
+	}
+
+	matched := make(Form, len(form))
+	keys := make(map[string]string, len(form))
+	for key, value := range form {
+		name := key
+		if !exact[key] {
+			candidates := normalized[m.normalize(key)]
+			if len(candidates) > 1 {
+				return nil, fmt.Errorf("form key '%s' matches multiple fields: %s", key, strings.Join(candidates, ", "))
+			} else if len(candidates) == 1 {
+				name = candidates[0]
+			}
+		}
+
+		if other, ok := keys[name]; ok {
+			return nil, fmt.Errorf("form keys '%s' and '%s' match the same field '%s'", other, key, name)
+		}
+		keys[name] = key
+		matched[name] = value
+	}
+	return matched, nil
+}
+
+// suggestField returns the field name closest to the unknown key or an
+// empty string if no field name is similar enough.
+func suggestField(key string, fields []Field) string {
+	norm := MatchNormalized.normalize(key)
+	maxDist := len([]rune(norm)) / 3
+	if maxDist < 1 {
+		maxDist = 1
+	}
+
+	var best string
+	bestDist := maxDist + 1
+	for _, f := range fields {
+		d := levenshtein(norm, MatchNormalized.normalize(f.Name))
+		if d < bestDist {
+			best, bestDist = f.Name, d
+		}
+	}
+	return best
+}
+
+// levenshtein returns the edit distance of the strings.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,242 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// MaxLenPolicy defines how values exceeding the maximum length
+// of their text fields are handled.
+type MaxLenPolicy int
+
+const (
+	// MaxLenIgnore fills the values as they are. Viewers usually
+	// hide the overflowing characters. This is the default.
+	MaxLenIgnore MaxLenPolicy = iota
+	// MaxLenTruncate truncates the values to the maximum length.
+	MaxLenTruncate
+	// MaxLenFail fails with a MaxLenError.
+	MaxLenFail
+	// MaxLenSplit continues the overflowing characters in the following
+	// text fields, whose names end with the next numbers, e.g. a value of
+	// "iban1" continues in "iban2", "iban3" and so on. Fields with values
+	// of their own are not overwritten. A MaxLenError is returned if the
+	// following fields are too short.
+	MaxLenSplit
+)
+
+// fieldNumberRegexp matches field names ending with a number.
+var fieldNumberRegexp = regexp.MustCompile(`^(.*?)(\d+)$`)
+
+// combSeparators are removed from overflowing values of comb fields,
+// e.g. the spaces of a grouped IBAN.
+var combSeparators = strings.NewReplacer(" ", "", "-", "", "/", "")
+
+// textLimit is the maximum length of a text field.
+// It is 0 for unlimited fields.
+type textLimit struct {
+	maxLen int
+	comb   bool
+}
+
+// fitMaxLen returns a copy of the form with the string values fitted into
+// their text fields as defined by the policy. Comb fields show one character
+// per box, so separators are removed from their overflowing values first.
+func fitMaxLen(form Form, formPDFFile, password string, policy MaxLenPolicy) (Form, error) {
+	limits, err := readTextLimits(formPDFFile, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read field lengths: %w", err)
+	}
+
+	fitted := make(Form, len(form))
+	var exceeded []string
+	for key, value := range form {
+		fitted[key] = value
+
+		s, ok := value.(string)
+		l, text := limits[key]
+		if !ok || !text || l.maxLen <= 0 || utf8.RuneCountInString(s) <= l.maxLen {
+			continue
+		}
+
+		if l.comb {
+			s = combSeparators.Replace(s)
+			fitted[key] = s
+			if utf8.RuneCountInString(s) <= l.maxLen {
+				continue
+			}
+		}
+
+		switch policy {
+		case MaxLenFail:
+			exceeded = append(exceeded, key)
+		case MaxLenSplit:
+			if !splitValue(fitted, form, limits, key, []rune(s)) {
+				exceeded = append(exceeded, key)
+			}
+		default:
+			fitted[key] = string([]rune(s)[:l.maxLen])
+		}
+	}
+
+	if len(exceeded) > 0 {
+		sort.Strings(exceeded)
+		return nil, &MaxLenError{Fields: exceeded}
+	}
+	return fitted, nil
+}
+
+// splitValue sets the value of the field and continues its overflowing
+// characters in the following fields. It returns false if the following
+// fields are missing, already have values or are too short.
+func splitValue(fitted, form Form, limits map[string]textLimit, key string, value []rune) bool {
+	n := limits[key].maxLen
+	fitted[key] = string(value[:n])
+	value = value[n:]
+
+	for next := key; len(value) > 0; {
+		next = nextFieldName(next)
+		l, ok := limits[next]
+		if !ok {
+			return false
+		} else if _, ok := form[next]; ok {
+			return false
+		}
+
+		n := len(value)
+		if l.maxLen > 0 && n > l.maxLen {
+			n = l.maxLen
+		}
+		fitted[next] = string(value[:n])
+		value = value[n:]
+	}
+	return true
+}
+
+// nextFieldName returns the field name with its trailing number
+// incremented, e.g. "iban2" for "iban1" and "ssn.09" for "ssn.08".
+// An empty string is returned if the name does not end with a number.
+func nextFieldName(name string) string {
+	m := fieldNumberRegexp.FindStringSubmatch(name)
+	if m == nil {
+		return ""
+	}
+	i, err := strconv.Atoi(m[2])
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%s%0*d", m[1], len(m[2]), i+1)
+}
+
+// readTextLimits returns the maximum lengths of the text fields
+// of the PDF file by their fully qualified names.
+func readTextLimits(pdfFile, password string) (map[string]textLimit, error) {
+	ctx, err := readPdfcpuContext(pdfFile, password)
+	if err != nil {
+		return nil, err
+	}
+
+	limits := make(map[string]textLimit)
+	err = walkFields(ctx, func(name string, d types.Dict) error {
+		if hasFieldKids(ctx, d) {
+			return nil
+		}
+		ft, err := inheritedEntry(ctx, d, "FT")
+		if err != nil {
+			return err
+		} else if n, ok := ft.(types.Name); !ok || n != "Tx" {
+			return nil
+		}
+
+		maxLen, err := inheritedInt(ctx, d, "MaxLen")
+		if err != nil {
+			return err
+		}
+		flags, err := inheritedFlags(ctx, d)
+		if err != nil {
+			return err
+		}
+		limits[name] = textLimit{maxLen: maxLen, comb: flags&flagComb != 0}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return limits, nil
+}
+
+// intEntry returns the integer entry of the dictionary or 0 if not set.
+func intEntry(ctx *model.Context, d types.Dict, key string) (int, error) {
+	o, err := ctx.Dereference(d[key])
+	if err != nil {
+		return 0, err
+	}
+	i, ok := o.(types.Integer)
+	if !ok {
+		return 0, nil
+	}
+	return i.Value(), nil
+}
+
+// inheritedFlags returns the field flags of the field dictionary,
+// which are inherited from the parent fields if not set.
+func inheritedFlags(ctx *model.Context, d types.Dict) (int, error) {
+	return inheritedInt(ctx, d, "Ff")
+}
+
+// inheritedInt is like inheritedEntry, but returns the integer
+// entry or 0 if not set.
+func inheritedInt(ctx *model.Context, d types.Dict, key string) (int, error) {
+	o, err := inheritedEntry(ctx, d, key)
+	if err != nil {
+		return 0, err
+	}
+	i, ok := o.(types.Integer)
+	if !ok {
+		return 0, nil
+	}
+	return i.Value(), nil
+}
+
+// inheritedEntry returns the dereferenced entry of the field dictionary,
+// which is inherited from the parent fields if not set.
+func inheritedEntry(ctx *model.Context, d types.Dict, key string) (types.Object, error) {
+	// Limit the depth to protect against cyclic references.
+	for i := 0; d != nil && i < 32; i++ {
+		if o, ok := d.Find(key); ok {
+			return ctx.Dereference(o)
+		}
+
+		var err error
+		d, err = ctx.DereferenceDict(d["Parent"])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,346 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	pdfform "github.com/pdfcpu/pdfcpu/pkg/pdfcpu/form"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// FillStream fills the form PDF read from r and writes the filled PDF to w.
+// The template, the form values and the filled PDF are kept in memory only
+// and no temporary files are created, e.g. for compliance environments
+// where personal data must never be written to disk.
+// The pdfcpu library is used to fill the form regardless of the Backend
+// option and forms are flattened like with the Pdfcpu backend.
+// Supported are the Flatten, DropXFA, InputPassword, Metrics and
+// TracerProvider options and the value options CheckboxOn, CheckboxStates,
+// RepeatPattern, TimeLayout, FieldTimeLayouts and ValueEncoder.
+// The Overwrite, TempDir and Backend options have no effect, all other
+// options and image values result in an error.
+// Radio values, list box selections and custom combo box values are
+// checked and set like with Fill.
+func FillStream(form Form, r io.Reader, w io.Writer, options ...Options) error {
+	return FillStreamContext(context.Background(), form, r, w, options...)
+}
+
+// FillStreamContext is like FillStream, but checks the context for
+// cancellation between the processing steps.
+func FillStreamContext(ctx context.Context, form Form, r io.Reader, w io.Writer, options ...Options) (err error) {
+	opts := getOptions(options)
+
+	// Observe the fill once it is done.
+	var outputBytes int64
+	defer func(start time.Time) { observeFill(opts.Metrics, start, outputBytes, err) }(time.Now())
+
+	ctx, span := opts.tracer().Start(ctx, "fillpdf.FillStream", trace.WithAttributes(
+		attribute.Int("fillpdf.form.fields", len(form)),
+	))
+	defer func() { endSpan(span, err) }()
+
+	if name := opts.unsupportedInMemory(); name != "" {
+		return fmt.Errorf("option is not supported in memory: %s", name)
+	}
+
+	form, err = opts.prepareForm(form)
+	if err != nil {
+		return err
+	}
+
+	radios := radioValues(form)
+	multi := multiValues(form)
+
+	form, err = opts.encodeValues(form)
+	if err != nil {
+		return err
+	}
+
+	form, images := splitImages(form)
+	if len(images) > 0 {
+		return fmt.Errorf("image values are not supported in memory")
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read form PDF: %w", err)
+	}
+
+	// Drop the XFA form before filling.
+	if opts.DropXFA {
+		data, err = dropXFABytes(data, opts.InputPassword)
+		if err != nil {
+			return fmt.Errorf("failed to drop XFA form: %w", err)
+		}
+	}
+
+	err = ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	// Check the values against the form fields like Fill does.
+	pdfCtx, err := readPdfcpuBytes(data, opts.InputPassword)
+	if err != nil {
+		return fmt.Errorf("failed to read form PDF: %w", err)
+	}
+	if len(radios) > 0 {
+		err = checkRadioValuesContext(pdfCtx, radios)
+		if err != nil {
+			return err
+		}
+	}
+	if len(multi) > 0 {
+		err = checkMultiValuesContext(pdfCtx, multi)
+		if err != nil {
+			return err
+		}
+	}
+	combos, err := customComboValuesContext(pdfCtx, form)
+	if err != nil {
+		return fmt.Errorf("failed to read combo box options: %w", err)
+	}
+
+	states, err := checkBoxStatesContext(pdfCtx, form)
+	if err != nil {
+		return fmt.Errorf("failed to read checkbox states: %w", err)
+	}
+
+	data, err = pdfcpuFillBytes(data, form, states, opts.InputPassword)
+	if err != nil {
+		return fmt.Errorf("pdfcpu error: %w", err)
+	}
+
+	// The backend drops the custom values of editable combo boxes.
+	if len(combos) > 0 {
+		data, err = setComboValuesBytes(data, opts.InputPassword, combos)
+		if err != nil {
+			return fmt.Errorf("failed to set combo box values: %w", err)
+		}
+	}
+
+	if opts.Flatten {
+		err = ctx.Err()
+		if err != nil {
+			return err
+		}
+
+		data, err = flattenBytes(data, opts.InputPassword)
+		if err != nil {
+			return fmt.Errorf("failed to flatten form: %w", err)
+		}
+	}
+
+	_, err = w.Write(data)
+	if err != nil {
+		return fmt.Errorf("failed to write filled PDF: %w", err)
+	}
+	outputBytes = int64(len(data))
+	span.SetAttributes(attribute.Int64("fillpdf.output.bytes", outputBytes))
+	return nil
+}
+
+// unsupportedInMemory returns the name of the first set option
+// requiring temporary files or external utilities or having no
+// effect on the in-memory fill.
+func (o Options) unsupportedInMemory() string {
+	switch {
+	case o.Logger != nil:
+		return "Logger"
+	case o.Encoding != EncodingUTF16:
+		return "Encoding"
+	case o.XFA:
+		return "XFA"
+	case o.PreserveTags:
+		return "PreserveTags"
+	case o.SyncXFA:
+		return "SyncXFA"
+	case o.CheckRequired:
+		return "CheckRequired"
+	case o.Strict:
+		return "Strict"
+	case o.Defaults:
+		return "Defaults"
+	case o.FieldMatching != MatchExact:
+		return "FieldMatching"
+	case o.NeedAppearances:
+		return "NeedAppearances"
+	case o.MaxLen != MaxLenIgnore:
+		return "MaxLen"
+	case o.Repair:
+		return "Repair"
+	case o.Verify:
+		return "Verify"
+	case o.ReadOnly:
+		return "ReadOnly"
+	case len(o.Appearances) > 0:
+		return "Appearances"
+	case len(o.Fonts) > 0:
+		return "Fonts"
+	case len(o.QRCodes) > 0:
+		return "QRCodes"
+	case len(o.Barcodes) > 0:
+		return "Barcodes"
+	case o.FlattenAnnotations:
+		return "FlattenAnnotations"
+	case len(o.Overlays) > 0:
+		return "Overlays"
+	case len(o.Rotate) > 0:
+		return "Rotate"
+	case o.Pages != "":
+		return "Pages"
+	case len(o.AppendPDFs) > 0:
+		return "AppendPDFs"
+	case o.Bates != nil:
+		return "Bates"
+	case o.NUp != nil:
+		return "NUp"
+	case o.RemoveMetadata:
+		return "RemoveMetadata"
+	case o.RemoveJavaScript:
+		return "RemoveJavaScript"
+	case o.Sanitize:
+		return "Sanitize"
+	case len(o.DocInfo) > 0:
+		return "DocInfo"
+	case o.PDFA != nil:
+		return "PDFA"
+	case len(o.Attachments) > 0:
+		return "Attachments"
+	case o.Optimize:
+		return "Optimize"
+	case o.Encryption != nil:
+		return "Encryption"
+	case o.Linearize:
+		return "Linearize"
+	case o.Signature != nil:
+		return "Signature"
+	}
+	return ""
+}
+
+func dropXFABytes(data []byte, password string) ([]byte, error) {
+	ctx, err := readPdfcpuBytes(data, password)
+	if err != nil {
+		return nil, err
+	}
+
+	err = dropXFAContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return writePdfcpuBytes(ctx)
+}
+
+func setComboValuesBytes(data []byte, password string, values map[string]string) ([]byte, error) {
+	ctx, err := readPdfcpuBytes(data, password)
+	if err != nil {
+		return nil, err
+	}
+
+	err = setComboValuesContext(ctx, values)
+	if err != nil {
+		return nil, err
+	}
+	return writePdfcpuBytes(ctx)
+}
+
+// readPdfcpuBytes reads and validates the PDF data with pdfcpu.
+func readPdfcpuBytes(data []byte, password string) (*model.Context, error) {
+	ctx, err := api.ReadAndValidate(bytes.NewReader(data), pdfcpuReadConfig(password))
+	if err != nil {
+		return nil, err
+	}
+
+	err = ctx.EnsurePageCount()
+	if err != nil {
+		return nil, err
+	}
+	return ctx, nil
+}
+
+// writePdfcpuBytes writes the pdfcpu context to a new buffer.
+func writePdfcpuBytes(ctx *model.Context) ([]byte, error) {
+	var out bytes.Buffer
+	err := api.WriteContext(ctx, &out)
+	if err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// pdfcpuFillBytes fills the form PDF data. The checkbox values are
+// checked against the on states of the checkboxes.
+// The data is returned unchanged if no field is affected.
+func pdfcpuFillBytes(data []byte, form Form, states map[string][]string, password string) ([]byte, error) {
+	fields, err := api.FormFields(bytes.NewReader(data), pdfcpuConfig(password))
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]pdfform.Field, len(fields))
+	for _, f := range fields {
+		m[f.Name] = f
+	}
+
+	f, n := pdfcpuFormData(form, m, states)
+	if n == 0 {
+		return data, nil
+	}
+
+	formData, err := json.Marshal(pdfform.FormGroup{Forms: []pdfform.Form{f}})
+	if err != nil {
+		return nil, err
+	}
+
+	// pdfcpu fails if the values equal the current ones.
+	var out bytes.Buffer
+	err = api.FillForm(bytes.NewReader(data), bytes.NewReader(formData), &out, pdfcpuConfig(password))
+	if errors.Is(err, api.ErrNoFormFieldsAffected) {
+		return data, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// flattenBytes flattens the form of the PDF data like the Pdfcpu backend.
+func flattenBytes(data []byte, password string) ([]byte, error) {
+	ctx, err := readPdfcpuBytes(data, password)
+	if err != nil {
+		return nil, err
+	}
+
+	err = flattenTaggedContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return writePdfcpuBytes(ctx)
+}
@@ -0,0 +1,69 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+)
+
+// Merge concatenates the input PDF files in the given order and writes
+// the result to the destination PDF file. An existing destination file is replaced.
+func Merge(destPDFFile string, inputPDFFiles ...string) error {
+	return MergeContext(context.Background(), destPDFFile, inputPDFFiles...)
+}
+
+// MergeContext is like Merge, but the context is used to cancel
+// the spawned external processes.
+func MergeContext(ctx context.Context, destPDFFile string, inputPDFFiles ...string) (err error) {
+	if len(inputPDFFiles) == 0 {
+		return fmt.Errorf("no input PDF files to merge")
+	}
+
+	// Get the absolute paths.
+	destPDFFile, err = filepath.Abs(destPDFFile)
+	if err != nil {
+		return fmt.Errorf("failed to create the absolute path: %w", err)
+	}
+	inputs, err := absExistingFiles(inputPDFFiles)
+	if err != nil {
+		return err
+	}
+
+	err = checkPdftk()
+	if err != nil {
+		return err
+	}
+
+	// Create a temporary directory and remove it on defer again.
+	tmpDir, err := createTempDir("")
+	if err != nil {
+		return err
+	}
+	defer removeTempDir(tmpDir)
+
+	outputFile := filepath.Join(tmpDir, "output.pdf")
+	err = pdftkCat(ctx, tmpDir, inputs, outputFile)
+	if err != nil {
+		return err
+	}
+
+	return writeDestFile(outputFile, destPDFFile, true)
+}
@@ -0,0 +1,113 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+)
+
+// RemoveMetadata removes the document info entries and the XMP metadata
+// of the input PDF file and writes the result to the destination file.
+// An existing destination file is replaced. No external utility is required.
+// The Producer, CreationDate and ModDate info entries are rewritten
+// by the PDF writer and therefore remain.
+func RemoveMetadata(inputPDFFile, destPDFFile string) error {
+	return RemoveMetadataContext(context.Background(), inputPDFFile, destPDFFile)
+}
+
+// RemoveMetadataContext is like RemoveMetadata, but checks the context
+// for cancellation before processing.
+func RemoveMetadataContext(ctx context.Context, inputPDFFile, destPDFFile string) (err error) {
+	// Get the absolute paths.
+	destPDFFile, err = filepath.Abs(destPDFFile)
+	if err != nil {
+		return fmt.Errorf("failed to create the absolute path: %w", err)
+	}
+	inputs, err := absExistingFiles([]string{inputPDFFile})
+	if err != nil {
+		return err
+	}
+
+	// Create a temporary directory and remove it on defer again.
+	tmpDir, err := createTempDir("")
+	if err != nil {
+		return err
+	}
+	defer removeTempDir(tmpDir)
+
+	outputFile := filepath.Join(tmpDir, "output.pdf")
+	err = removeMetadataPass(ctx, tmpDir, inputs[0], outputFile)
+	if err != nil {
+		return err
+	}
+
+	return writeDestFile(outputFile, destPDFFile, true)
+}
+
+func removeMetadataPass(ctx context.Context, tmpDir, inputFile, outputFile string) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	err = removeMetadata(inputFile, outputFile)
+	if err != nil {
+		return fmt.Errorf("failed to remove metadata: %w", err)
+	}
+	return nil
+}
+
+// removeMetadata clears the document info dictionary and removes the
+// XMP metadata streams of the document and its pages.
+func removeMetadata(inputFile, outputFile string) error {
+	ctx, err := readPdfcpuContext(inputFile, "")
+	if err != nil {
+		return err
+	}
+
+	// Clear the info dictionary.
+	if ctx.Info != nil {
+		info, err := ctx.DereferenceDict(*ctx.Info)
+		if err != nil {
+			return err
+		}
+		for key := range info {
+			info.Delete(key)
+		}
+	}
+
+	// Remove the XMP metadata streams.
+	root, err := ctx.Catalog()
+	if err != nil {
+		return err
+	}
+	root.Delete("Metadata")
+
+	for page := 1; page <= ctx.PageCount; page++ {
+		d, _, _, err := ctx.PageDict(page, false)
+		if err != nil {
+			return err
+		}
+		d.Delete("Metadata")
+	}
+
+	return writePdfcpuContext(ctx, outputFile)
+}
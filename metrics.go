@@ -0,0 +1,75 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+)
+
+// Metrics receives the observations of fills and external commands,
+// e.g. to update Prometheus or statsd counters and histograms.
+// The methods are called concurrently and must not block.
+type Metrics interface {
+	// ObserveFill is called after every fill with its duration.
+	// The output size is the size of the filled PDF in bytes,
+	// which is zero if the fill failed with the error.
+	ObserveFill(duration time.Duration, outputBytes int64, err error)
+	// ObserveCommand is called after every external command with
+	// the base name of the binary, e.g. "pdftk", the duration and the
+	// exit code. The exit code is -1 if the command did not exit.
+	ObserveCommand(name string, duration time.Duration, exitCode int)
+}
+
+type metricsKey struct{}
+
+// metricsFrom returns the metrics of the context or nil.
+func metricsFrom(ctx context.Context) Metrics {
+	m, _ := ctx.Value(metricsKey{}).(Metrics)
+	return m
+}
+
+// instrument returns the context carrying the logger and the metrics
+// of the options, which observe the external commands.
+func (o Options) instrument(ctx context.Context) context.Context {
+	ctx = o.withLogger(ctx)
+	if o.Metrics != nil {
+		ctx = context.WithValue(ctx, metricsKey{}, o.Metrics)
+	}
+	return ctx
+}
+
+// observeFill passes the fill to the metrics, if set.
+func observeFill(m Metrics, start time.Time, outputBytes int64, err error) {
+	if m == nil {
+		return
+	}
+	if err != nil {
+		outputBytes = 0
+	}
+	m.ObserveFill(time.Since(start), outputBytes, err)
+}
+
+// observeCommand passes the external command to the metrics of the context.
+func observeCommand(ctx context.Context, name string, duration time.Duration, exitCode int) {
+	if m := metricsFrom(ctx); m != nil {
+		m.ObserveCommand(filepath.Base(name), duration, exitCode)
+	}
+}
@@ -0,0 +1,98 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+)
+
+// NUpOrder is the order in which the pages are placed onto a sheet.
+type NUpOrder string
+
+// The page orders of a sheet.
+const (
+	// NUpRightDown fills the rows from left to right, starting at the top.
+	NUpRightDown NUpOrder = "rd"
+	// NUpDownRight fills the columns from top to bottom, starting at the left.
+	NUpDownRight NUpOrder = "dr"
+	// NUpLeftDown fills the rows from right to left, starting at the top.
+	NUpLeftDown NUpOrder = "ld"
+	// NUpDownLeft fills the columns from top to bottom, starting at the right.
+	NUpDownLeft NUpOrder = "dl"
+)
+
+// NUp imposes several pages onto each sheet, e.g. labels or tickets filled
+// from the same template. The pages are scaled to fit the grid cells of the
+// sheet keeping their aspect ratio. Only the page contents are imposed, so
+// the values of form fields are only contained if the form is flattened
+// by pdftk or with PreserveTags.
+type NUp struct {
+	// Pages is the number of pages per sheet: 2, 3, 4, 6, 8, 9, 12 or 16.
+	Pages int
+	// PaperSize is the sheet size, e.g. "A4", "Letter" or "A4L" for
+	// landscape. Defaults to "A4" if empty.
+	PaperSize string
+	// Order is the order of the pages. Defaults to NUpRightDown if empty.
+	Order NUpOrder
+	// Margin is the margin around each page in points.
+	Margin float64
+	// Border draws a border around each page.
+	Border bool
+}
+
+func (n NUp) withDefaults() NUp {
+	if n.PaperSize == "" {
+		n.PaperSize = "A4"
+	}
+	if n.Order == "" {
+		n.Order = NUpRightDown
+	}
+	return n
+}
+
+func (n *NUp) pass(ctx context.Context, tmpDir, inputFile, outputFile string) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	err = imposeNUp(inputFile, outputFile, n.withDefaults())
+	if err != nil {
+		return fmt.Errorf("failed to impose pages: %w", err)
+	}
+	return nil
+}
+
+func imposeNUp(inputFile, outputFile string, n NUp) error {
+	border := "off"
+	if n.Border {
+		border = "on"
+	}
+	desc := fmt.Sprintf("formsize:%s, orientation:%s, margin:%.2f, border:%s", n.PaperSize, n.Order, n.Margin, border)
+
+	conf := pdfcpuReadConfig("")
+	nup, err := api.PDFNUpConfig(n.Pages, desc, conf)
+	if err != nil {
+		return err
+	}
+	return api.NUpFile([]string{inputFile}, outputFile, nil, nup, conf)
+}
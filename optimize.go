@@ -0,0 +1,92 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/filter"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+func optimizePass(ctx context.Context, tmpDir, inputFile, outputFile string) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	err = optimize(inputFile, outputFile)
+	if err != nil {
+		return fmt.Errorf("failed to optimize PDF: %w", err)
+	}
+	return nil
+}
+
+// optimize removes unused objects, deduplicates fonts and images,
+// compresses uncompressed streams and writes object streams.
+func optimize(inputFile, outputFile string) error {
+	in, err := os.Open(inputFile)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	ctx, err := api.ReadValidateAndOptimize(in, pdfcpuReadConfig(""))
+	if err != nil {
+		return err
+	}
+
+	err = compressStreams(ctx)
+	if err != nil {
+		return err
+	}
+
+	return writePdfcpuContext(ctx, outputFile)
+}
+
+// compressStreams flate encodes all unfiltered streams.
+// Metadata streams are kept readable, as required by PDF/A.
+func compressStreams(ctx *model.Context) error {
+	for _, entry := range ctx.Table {
+		if entry == nil || entry.Free || entry.Object == nil {
+			continue
+		}
+
+		sd, ok := entry.Object.(types.StreamDict)
+		if !ok || len(sd.FilterPipeline) > 0 || sd.Raw == nil {
+			continue
+		} else if t := sd.Type(); t != nil && *t == "Metadata" {
+			continue
+		}
+
+		sd.Content = sd.Raw
+		sd.FilterPipeline = []types.PDFFilter{{Name: filter.Flate}}
+		sd.InsertName("Filter", filter.Flate)
+		err := sd.Encode()
+		if err != nil {
+			return err
+		}
+		entry.Object = sd
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image/color"
+	"os"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// Overlay is a text placed onto a page of the filled PDF, e.g. for
+// templates with visual blanks but no form fields.
+type Overlay struct {
+	// Page is the page number starting with 1.
+	Page int
+	// X and Y are the lower left corner of the text in PDF user space units.
+	X, Y float64
+	// Text is the overlay text. Lines are separated by newlines.
+	Text string
+	// Font is the name of one of the standard 14 fonts. Defaults to Helvetica.
+	Font string
+	// FontSize is the font size in points. Defaults to 10 if zero.
+	FontSize int
+	// Rotation is the counterclockwise rotation in degrees around the text center.
+	Rotation float64
+	// Color is the text color. Defaults to black.
+	Color color.Color
+}
+
+func (o Overlay) withDefaults() Overlay {
+	if o.Font == "" {
+		o.Font = "Helvetica"
+	}
+	if o.FontSize == 0 {
+		o.FontSize = 10
+	}
+	if o.Color == nil {
+		o.Color = color.Black
+	}
+	return o
+}
+
+// overlaysPass returns a pass placing the overlays onto their pages.
+func overlaysPass(overlays []Overlay) pass {
+	return func(ctx context.Context, tmpDir, inputFile, outputFile string) error {
+		err := ctx.Err()
+		if err != nil {
+			return err
+		}
+
+		err = stampOverlays(inputFile, outputFile, overlays)
+		if err != nil {
+			return fmt.Errorf("failed to place overlays: %w", err)
+		}
+		return nil
+	}
+}
+
+func stampOverlays(inputFile, outputFile string, overlays []Overlay) error {
+	ctx, err := readPdfcpuContext(inputFile, "")
+	if err != nil {
+		return err
+	}
+
+	m := make(map[int][]*model.Watermark)
+	for _, o := range overlays {
+		o = o.withDefaults()
+		if o.Page < 1 || o.Page > ctx.PageCount {
+			return fmt.Errorf("invalid page number %d of overlay '%s'", o.Page, o.Text)
+		} else if _, ok := standardFonts[o.Font]; !ok {
+			return fmt.Errorf("invalid overlay font: '%s'", o.Font)
+		}
+
+		// The stamp offset is relative to the visible page region.
+		_, _, inh, err := ctx.PageDict(o.Page, false)
+		if err != nil {
+			return err
+		}
+		viewport := inh.MediaBox
+		if inh.CropBox != nil {
+			viewport = inh.CropBox
+		}
+
+		r, g, b, _ := o.Color.RGBA()
+		desc := fmt.Sprintf("fontname:%s, points:%d, position:bl, offset:%.2f %.2f, scalefactor:1 abs, rotation:%.2f, fillcolor:#%02x%02x%02x",
+			o.Font, o.FontSize, o.X-viewport.LL.X, o.Y-viewport.LL.Y, o.Rotation, r>>8, g>>8, b>>8)
+		wm, err := api.TextWatermark(o.Text, desc, true, false, types.POINTS)
+		if err != nil {
+			return err
+		}
+		m[o.Page] = append(m[o.Page], wm)
+	}
+
+	in, err := os.Open(inputFile)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	var out bytes.Buffer
+	err = api.AddWatermarksSliceMap(in, &out, m, pdfcpuReadConfig(""))
+	if err != nil {
+		return err
+	}
+
+	return writeFile(outputFile, &out)
+}
@@ -0,0 +1,67 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// pageRangeRegexp matches a page range in pdftk syntax,
+// e.g. "5", "1-3", "4-end", "r1" or "1-endodd".
+var pageRangeRegexp = regexp.MustCompile(`^(r?\d+|r?end)(-(r?\d+|r?end))?(even|odd)?$`)
+
+// pageRangeArgs returns the pdftk cat arguments of the comma separated
+// page ranges, e.g. "1-3,5" results in "1-3" and "5".
+func pageRangeArgs(pages string) ([]string, error) {
+	var args []string
+	for _, r := range strings.Split(pages, ",") {
+		r = strings.TrimSpace(r)
+		if !pageRangeRegexp.MatchString(r) {
+			return nil, fmt.Errorf("invalid page range: '%s'", r)
+		}
+		args = append(args, r)
+	}
+	return args, nil
+}
+
+// selectPagesPass returns a pass keeping only the pages of the comma
+// separated page ranges in their order with pdftk's cat operation.
+func selectPagesPass(pages string) pass {
+	return func(ctx context.Context, tmpDir, inputFile, outputFile string) error {
+		args, err := pageRangeArgs(pages)
+		if err != nil {
+			return err
+		}
+
+		err = checkPdftk()
+		if err != nil {
+			return err
+		}
+
+		args = append(append([]string{inputFile, "cat"}, args...), "output", outputFile)
+		err = runPdftk(ctx, tmpDir, args...)
+		if err != nil {
+			return fmt.Errorf("failed to select pages: %w", err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,121 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+)
+
+// pass transforms the PDF input file into the output file.
+// Intermediate files may be created within tmpDir.
+type pass func(ctx context.Context, tmpDir, inputFile, outputFile string) error
+
+// passes returns the post processing passes defined by the options.
+// The order matters, the encryption, linearization and signature must be applied last.
+// Every pass is traced with its own span. Options of new passes
+// must also be cleared by partOptions.
+func (o Options) passes() (passes []pass) {
+	if o.FlattenAnnotations {
+		passes = append(passes, tracedPass("annotations", flattenAnnotationsPass))
+	}
+	if len(o.Overlays) > 0 {
+		passes = append(passes, tracedPass("overlays", overlaysPass(o.Overlays)))
+	}
+	if len(o.Rotate) > 0 {
+		passes = append(passes, tracedPass("rotate", rotatePass(o.Rotate)))
+	}
+	if o.Pages != "" {
+		passes = append(passes, tracedPass("pages", selectPagesPass(o.Pages)))
+	}
+	if len(o.AppendPDFs) > 0 {
+		passes = append(passes, tracedPass("append", appendPDFsPass(o.AppendPDFs)))
+	}
+	if o.Bates != nil {
+		passes = append(passes, tracedPass("bates", o.Bates.pass))
+	}
+	if o.NUp != nil {
+		passes = append(passes, tracedPass("nup", o.NUp.pass))
+	}
+	if o.RemoveMetadata {
+		passes = append(passes, tracedPass("metadata", removeMetadataPass))
+	}
+	if o.RemoveJavaScript {
+		passes = append(passes, tracedPass("javascript", removeJavaScriptPass))
+	}
+	if o.Sanitize {
+		passes = append(passes, tracedPass("sanitize", sanitizePass))
+	}
+	if len(o.DocInfo) > 0 {
+		passes = append(passes, tracedPass("docinfo", docInfoPass(o.DocInfo)))
+	}
+	if o.PDFA != nil {
+		passes = append(passes, tracedPass("pdfa", o.PDFA.pass))
+	}
+	if len(o.Attachments) > 0 {
+		passes = append(passes, tracedPass("attachments", attachFilesPass(o.Attachments)))
+	}
+	if o.Optimize {
+		passes = append(passes, tracedPass("optimize", optimizePass))
+	}
+	if o.Encryption != nil {
+		passes = append(passes, tracedPass("encryption", o.Encryption.pass))
+	}
+	if o.Linearize {
+		passes = append(passes, tracedPass("linearize", linearizePass(o.outputPassword())))
+	}
+	if o.Signature != nil {
+		passes = append(passes, tracedPass("signature", o.Signature.pass))
+	}
+	return
+}
+
+// outputPassword returns the password opening the encrypted output PDF
+// or an empty password if it is not encrypted.
+func (o Options) outputPassword() string {
+	if o.Encryption == nil {
+		return ""
+	} else if o.Encryption.OwnerPassword != "" {
+		return o.Encryption.OwnerPassword
+	}
+	return o.Encryption.UserPassword
+}
+
+// runPasses applies the passes in order to the input file and
+// returns the path of the final output file.
+func runPasses(ctx context.Context, tmpDir, inputFile string, passes []pass) (string, error) {
+	for i, p := range passes {
+		outputFile := filepath.Join(tmpDir, fmt.Sprintf("pass-%d.pdf", i))
+		err := p(ctx, tmpDir, inputFile, outputFile)
+		if err != nil {
+			return "", err
+		}
+		inputFile = outputFile
+	}
+	return inputFile, nil
+}
+
+// flattenPass returns a pass flattening the document with the backend
+// by filling no values.
+func flattenPass(b Backend) pass {
+	return func(ctx context.Context, tmpDir, inputFile, outputFile string) error {
+		return b.Fill(ctx, tmpDir, Form{}, inputFile, outputFile, Options{Flatten: true})
+	}
+}
@@ -0,0 +1,129 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// defaultICCProfile is the sRGB profile shipped with Ghostscript.
+const defaultICCProfile = "%rom%iccprofiles/srgb.icc"
+
+// PDFA converts the output to a PDF/A document for archival.
+// The document should be flattened, because PDF/A forbids
+// interactive form elements without appearances.
+// PDF/A can not be combined with encryption.
+type PDFA struct {
+	// Part is the PDF/A part: 1, 2 or 3. Defaults to 2 (PDF/A-2b) if zero.
+	Part int
+	// ICCProfile is the path of the RGB output intent profile.
+	// Defaults to the sRGB profile shipped with Ghostscript.
+	ICCProfile string
+}
+
+// pass converts the input file with Ghostscript.
+func (p *PDFA) pass(ctx context.Context, tmpDir, inputFile, outputFile string) error {
+	gs, err := ghostscriptBinary()
+	if err != nil {
+		return err
+	}
+
+	part := p.Part
+	if part == 0 {
+		part = 2
+	} else if part < 1 || part > 3 {
+		return fmt.Errorf("invalid PDF/A part: %d", part)
+	}
+
+	profile := p.ICCProfile
+	if profile == "" {
+		profile = defaultICCProfile
+	} else {
+		profile, err = filepath.Abs(profile)
+		if err != nil {
+			return fmt.Errorf("failed to create the absolute path: %w", err)
+		}
+	}
+
+	// The PDF/A definition file embeds the output intent.
+	defFile := filepath.Join(tmpDir, "pdfa_def.ps")
+	err = writeFile(defFile, strings.NewReader(pdfaDef(profile)))
+	if err != nil {
+		return fmt.Errorf("failed to write PDF/A definition file: %w", err)
+	}
+
+	err = runCommandInPath(ctx, tmpDir, gs,
+		"-dPDFA="+strconv.Itoa(part),
+		"-dBATCH",
+		"-dNOPAUSE",
+		"-dQUIET",
+		"-dNOOUTERSAVE",
+		"-dPDFACompatibilityPolicy=1",
+		"-sColorConversionStrategy=RGB",
+		"-sDEVICE=pdfwrite",
+		"--permit-file-read="+profile,
+		"-sOutputFile="+outputFile,
+		defFile,
+		inputFile,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to convert to PDF/A: ghostscript error: %w", err)
+	}
+	return nil
+}
+
+// pdfaDef returns the PostScript PDF/A definition embedding the ICC profile
+// as output intent.
+func pdfaDef(profile string) string {
+	return `%!
+/ICCProfile ` + psString(profile) + ` def
+[/_objdef {icc_PDFA} /type /stream /OBJ pdfmark
+[{icc_PDFA} <</N 3>> /PUT pdfmark
+[{icc_PDFA} ICCProfile (r) file /PUT pdfmark
+[/_objdef {OutputIntent_PDFA} /type /dict /OBJ pdfmark
+[{OutputIntent_PDFA} <<
+  /Type /OutputIntent
+  /S /GTS_PDFA1
+  /DestOutputProfile {icc_PDFA}
+  /OutputConditionIdentifier (sRGB)
+>> /PUT pdfmark
+[{Catalog} <</OutputIntents [ {OutputIntent_PDFA} ]>> /PUT pdfmark
+`
+}
+
+// psString returns the PostScript string literal of s.
+func psString(s string) string {
+	return "(" + strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(s) + ")"
+}
+
+// ghostscriptBinary returns the path of the Ghostscript binary.
+func ghostscriptBinary() (string, error) {
+	for _, name := range ghostscriptNames {
+		path, err := exec.LookPath(name)
+		if err == nil {
+			return path, nil
+		}
+	}
+	return lookInstallPath(ghostscriptInstallPaths(), ErrGhostscriptNotFound)
+}
@@ -0,0 +1,336 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	pdfform "github.com/pdfcpu/pdfcpu/pkg/pdfcpu/form"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// pdfcpuConfig returns a new pdfcpu configuration.
+// The password is used to decrypt password protected PDF files.
+// pdfcpu installs its configuration and fonts into the user's configuration
+// directory on first use. Set model.ConfigPath to change this behaviour.
+func pdfcpuConfig(password string) *model.Configuration {
+	conf := model.NewDefaultConfiguration()
+	conf.UserPW = password
+	conf.OwnerPW = password
+	return conf
+}
+
+// pdfcpuReadConfig returns a new pdfcpu configuration for operations
+// requiring no user fonts. Unlike pdfcpuConfig, the user's configuration
+// directory is never touched. The values match the pdfcpu defaults.
+func pdfcpuReadConfig(password string) *model.Configuration {
+	return &model.Configuration{
+		CreationDate:          time.Now().Format("2006-01-02 15:04"),
+		Version:               model.VersionStr,
+		Reader15:              true,
+		ValidationMode:        model.ValidationRelaxed,
+		Eol:                   types.EolLF,
+		WriteObjectStream:     true,
+		WriteXRefStream:       true,
+		EncryptUsingAES:       true,
+		EncryptKeyLength:      256,
+		Permissions:           model.PermissionsPrint,
+		TimestampFormat:       "2006-01-02 15:04",
+		DateFormat:            "2006-01-02",
+		Optimize:              true,
+		OptimizeBeforeWriting: true,
+		OptimizeResourceDicts: true,
+		Timeout:               5,
+		UserPW:                password,
+		OwnerPW:               password,
+	}
+}
+
+// pdfcpuBackend fills the form in pure Go with the pdfcpu library.
+// pdfcpu is not able to flatten a document, so the widget appearances
+// generated by pdfcpu are painted into the page contents afterwards.
+type pdfcpuBackend struct{}
+
+func (pdfcpuBackend) Fill(ctx context.Context, tmpDir string, form Form, formPDFFile, outputFile string, opts Options) (err error) {
+	// pdfcpu does not support cancellation, so check at least once.
+	err = ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	// Drop the XFA form before filling.
+	if opts.DropXFA {
+		noXFAFile := filepath.Clean(tmpDir + "/form-noxfa.pdf")
+		err = dropXFA(formPDFFile, noXFAFile, opts.InputPassword)
+		if err != nil {
+			return fmt.Errorf("failed to drop XFA form: %w", err)
+		}
+		formPDFFile = noXFAFile
+	}
+
+	// Obtain the form fields to pass the values with their correct type.
+	fields, err := pdfcpuFormFields(formPDFFile, opts.InputPassword)
+	if err != nil {
+		return fmt.Errorf("failed to read form fields: %w", err)
+	}
+
+	// Checkboxes are only checked by one of their on states.
+	var states map[string][]string
+	if hasCheckBoxValues(form, fields) {
+		states, err = checkBoxStates(form, formPDFFile, opts.InputPassword)
+		if err != nil {
+			return fmt.Errorf("failed to read checkbox states: %w", err)
+		}
+	}
+
+	// Create the pdfcpu form data.
+	f, n := pdfcpuFormData(form, fields, states)
+
+	// Write the filled PDF to an intermediate file if it is flattened
+	// or decrypted afterwards.
+	filledFile := outputFile
+	if opts.Flatten || opts.InputPassword != "" {
+		filledFile = filepath.Clean(tmpDir + "/filled.pdf")
+	}
+
+	// pdfcpu fails if no field is affected, so just copy the form PDF.
+	if n == 0 {
+		err = copyFile(formPDFFile, filledFile)
+	} else {
+		err = pdfcpuFill(formPDFFile, filledFile, f, opts.InputPassword)
+	}
+	if err != nil {
+		return fmt.Errorf("pdfcpu error: %w", err)
+	}
+
+	// pdfcpu keeps the encryption of the form PDF, but like pdftk the
+	// filled PDF is written without it, so the following passes are able
+	// to read it without password.
+	if opts.Flatten {
+		err = pdfcpuFlatten(filledFile, outputFile, opts.InputPassword)
+		if err != nil {
+			return fmt.Errorf("failed to flatten form: %w", err)
+		}
+	} else if opts.InputPassword != "" {
+		err = pdfcpuDecrypt(filledFile, outputFile, opts.InputPassword)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt filled PDF: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// pdfcpuFormFields returns the form fields of the PDF file mapped by their names.
+func pdfcpuFormFields(path, password string) (map[string]pdfform.Field, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	fields, err := api.FormFields(in, pdfcpuConfig(password))
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]pdfform.Field, len(fields))
+	for _, f := range fields {
+		m[f.Name] = f
+	}
+	return m, nil
+}
+
+// pdfcpuFormData converts the form values to the pdfcpu form data.
+// The checkbox values are checked against the on states of the checkboxes.
+// Values without a matching field are skipped.
+// The number of added values is returned.
+func pdfcpuFormData(form Form, fields map[string]pdfform.Field, states map[string][]string) (f pdfform.Form, n int) {
+	for key, value := range form {
+		field, ok := fields[key]
+		if !ok {
+			continue
+		}
+
+		switch field.Typ {
+		case pdfform.FTText:
+			f.TextFields = append(f.TextFields, &pdfform.TextField{Name: key, Value: formatValue(value)})
+		case pdfform.FTDate:
+			f.DateFields = append(f.DateFields, &pdfform.DateField{Name: key, Value: formatValue(value)})
+		case pdfform.FTCheckBox:
+			f.CheckBoxes = append(f.CheckBoxes, &pdfform.CheckBox{Name: key, Value: isChecked(value, states[key])})
+		case pdfform.FTComboBox:
+			f.ComboBoxes = append(f.ComboBoxes, &pdfform.ComboBox{Name: key, Value: formatValue(value)})
+		case pdfform.FTRadioButtonGroup:
+			f.RadioButtonGroups = append(f.RadioButtonGroups, &pdfform.RadioButtonGroup{Name: key, Value: formatValue(value)})
+		case pdfform.FTListBox:
+			lb := &pdfform.ListBox{Name: key}
+			if values, ok := value.([]string); ok {
+				lb.Values = values
+			} else {
+				lb.Values = []string{formatValue(value)}
+			}
+			f.ListBoxes = append(f.ListBoxes, lb)
+		default:
+			continue
+		}
+		n++
+	}
+	return
+}
+
+// isChecked returns whether the value checks a checkbox with the on
+// states. Like with pdftk, string values are only checked if they
+// match one of the on states, other values like "No" are off.
+func isChecked(value interface{}, states []string) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case nil:
+		return false
+	}
+
+	s := formatValue(value)
+	for _, state := range states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// hasCheckBoxValues returns whether the form has values of checkboxes.
+func hasCheckBoxValues(form Form, fields map[string]pdfform.Field) bool {
+	for key := range form {
+		if f, ok := fields[key]; ok && f.Typ == pdfform.FTCheckBox {
+			return true
+		}
+	}
+	return false
+}
+
+// checkBoxStates returns the on states of the checkboxes of the form
+// values within the PDF file mapped by their names.
+func checkBoxStates(form Form, pdfFile, password string) (map[string][]string, error) {
+	ctx, err := readPdfcpuContext(pdfFile, password)
+	if err != nil {
+		return nil, err
+	}
+	return checkBoxStatesContext(ctx, form)
+}
+
+// checkBoxStatesContext is like checkBoxStates, but reads the
+// checkboxes of the pdfcpu context.
+func checkBoxStatesContext(ctx *model.Context, form Form) (map[string][]string, error) {
+	states := make(map[string][]string)
+	err := walkFields(ctx, func(name string, d types.Dict) error {
+		if _, ok := form[name]; !ok {
+			return nil
+		}
+
+		_, typ, err := widgetField(ctx, d)
+		if err != nil || typ != "Btn" {
+			return err
+		}
+		flags, err := inheritedFlags(ctx, d)
+		if err != nil {
+			return err
+		} else if flags&(flagRadio|flagPushbutton) != 0 {
+			return nil
+		}
+
+		// The on states are the appearance states like with radio buttons.
+		states[name], err = radioStates(ctx, d)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
+func pdfcpuFill(formPDFFile, outputFile string, f pdfform.Form, password string) (err error) {
+	data, err := json.Marshal(pdfform.FormGroup{Forms: []pdfform.Form{f}})
+	if err != nil {
+		return err
+	}
+
+	in, err := os.Open(formPDFFile)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	// pdfcpu fails if the values equal the current ones.
+	var out bytes.Buffer
+	err = api.FillForm(in, bytes.NewReader(data), &out, pdfcpuConfig(password))
+	if errors.Is(err, api.ErrNoFormFieldsAffected) {
+		return copyFile(formPDFFile, outputFile)
+	} else if err != nil {
+		return err
+	}
+
+	return writeFile(outputFile, &out)
+}
+
+// pdfcpuFlatten paints the appearances of the form field widgets
+// into the page contents and removes the form. The output file is
+// written without encryption.
+func pdfcpuFlatten(inputFile, outputFile, password string) error {
+	ctx, err := readPdfcpuContext(inputFile, password)
+	if err != nil {
+		return err
+	}
+
+	err = flattenTaggedContext(ctx)
+	if err != nil {
+		return err
+	}
+	removeEncryption(ctx)
+	return writePdfcpuContext(ctx, outputFile)
+}
+
+// pdfcpuDecrypt writes the input file without encryption to the output
+// file. Unlike api.DecryptFile, unencrypted input files are accepted.
+func pdfcpuDecrypt(inputFile, outputFile, password string) error {
+	ctx, err := readPdfcpuContext(inputFile, password)
+	if err != nil {
+		return err
+	}
+
+	removeEncryption(ctx)
+	return writePdfcpuContext(ctx, outputFile)
+}
+
+// removeEncryption drops the encryption of the read document
+// when the pdfcpu context is written.
+func removeEncryption(ctx *model.Context) {
+	if ctx.Encrypt != nil {
+		ctx.Cmd = model.DECRYPT
+	}
+}
@@ -0,0 +1,48 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import "testing"
+
+func TestIsChecked(t *testing.T) {
+	states := []string{"Yes"}
+	tests := []struct {
+		value  interface{}
+		states []string
+		want   bool
+	}{
+		{value: true, states: states, want: true},
+		{value: false, states: states, want: false},
+		{value: nil, states: states, want: false},
+		{value: "Yes", states: states, want: true},
+		{value: "No", states: states, want: false},
+		{value: "Off", states: states, want: false},
+		{value: "", states: states, want: false},
+		{value: "true", states: states, want: false},
+		{value: "On", states: []string{"On", "Yes"}, want: true},
+		{value: "Yes", states: nil, want: false},
+		{value: true, states: nil, want: true},
+	}
+	for _, tt := range tests {
+		got := isChecked(tt.value, tt.states)
+		if got != tt.want {
+			t.Errorf("isChecked(%v, %q) = %v, want %v", tt.value, tt.states, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"bytes"
+	"os"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// Rect is a rectangle in PDF user space units (points) defined
+// by its lower left and upper right corner.
+type Rect struct {
+	LLX, LLY float64
+	URX, URY float64
+}
+
+// Width returns the width of the rectangle.
+func (r Rect) Width() float64 {
+	return r.URX - r.LLX
+}
+
+// Height returns the height of the rectangle.
+func (r Rect) Height() float64 {
+	return r.URY - r.LLY
+}
+
+func rectFromPdfcpu(r *types.Rectangle) Rect {
+	return Rect{LLX: r.LL.X, LLY: r.LL.Y, URX: r.UR.X, URY: r.UR.Y}
+}
+
+// pdfFieldTypeSignature is the PDF field type of signature fields.
+const pdfFieldTypeSignature = "Sig"
+
+// widget is the placement of a form field widget annotation.
+type widget struct {
+	// Name is the fully qualified field name.
+	Name string
+	// Type is the PDF field type: Tx, Btn, Ch or Sig.
+	Type string
+	// Page is the page number starting with 1.
+	Page int
+	// Rect is the widget rectangle.
+	Rect Rect
+	// Viewport is the visible page region.
+	Viewport Rect
+}
+
+// readPdfcpuContext reads and validates the PDF file with pdfcpu.
+func readPdfcpuContext(pdfFile, password string) (*model.Context, error) {
+	in, err := os.Open(pdfFile)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	ctx, err := api.ReadAndValidate(in, pdfcpuReadConfig(password))
+	if err != nil {
+		return nil, err
+	}
+
+	err = ctx.EnsurePageCount()
+	if err != nil {
+		return nil, err
+	}
+	return ctx, nil
+}
+
+// writePdfcpuContext writes the pdfcpu context to the output file.
+func writePdfcpuContext(ctx *model.Context, outputFile string) error {
+	var out bytes.Buffer
+	err := api.WriteContext(ctx, &out)
+	if err != nil {
+		return err
+	}
+	return writeFile(outputFile, &out)
+}
+
+// acroForm returns the AcroForm dictionary of the document.
+// Nil is returned if the document has no form.
+func acroForm(ctx *model.Context) (types.Dict, error) {
+	root, err := ctx.Catalog()
+	if err != nil {
+		return nil, err
+	}
+	return ctx.DereferenceDict(root["AcroForm"])
+}
+
+// readWidgets returns the form field widget annotations of the PDF file.
+func readWidgets(pdfFile, password string) ([]widget, error) {
+	ctx, err := readPdfcpuContext(pdfFile, password)
+	if err != nil {
+		return nil, err
+	}
+
+	var widgets []widget
+	for page := 1; page <= ctx.PageCount; page++ {
+		pageDict, _, inh, err := ctx.PageDict(page, false)
+		if err != nil {
+			return nil, err
+		}
+
+		viewport := inh.MediaBox
+		if inh.CropBox != nil {
+			viewport = inh.CropBox
+		}
+
+		annots, err := ctx.DereferenceArray(pageDict["Annots"])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, o := range annots {
+			d, err := ctx.DereferenceDict(o)
+			if err != nil {
+				return nil, err
+			} else if d == nil || d.Subtype() == nil || *d.Subtype() != "Widget" {
+				continue
+			}
+
+			rectArr, err := ctx.DereferenceArray(d["Rect"])
+			if err != nil {
+				return nil, err
+			} else if len(rectArr) != 4 {
+				continue
+			}
+			rect, err := ctx.RectForArray(rectArr)
+			if err != nil {
+				return nil, err
+			}
+
+			name, typ, err := widgetField(ctx, d)
+			if err != nil {
+				return nil, err
+			} else if name == "" {
+				continue
+			}
+
+			widgets = append(widgets, widget{
+				Name:     name,
+				Type:     typ,
+				Page:     page,
+				Rect:     rectFromPdfcpu(rect),
+				Viewport: rectFromPdfcpu(viewport),
+			})
+		}
+	}
+	return widgets, nil
+}
+
+// widgetField returns the fully qualified field name and the field type
+// of the widget annotation by walking up the field hierarchy.
+func widgetField(ctx *model.Context, d types.Dict) (name, typ string, err error) {
+	var parts []string
+
+	// Limit the depth to protect against cyclic references.
+	for i := 0; d != nil && i < 32; i++ {
+		if o, ok := d.Find("T"); ok {
+			o, err = ctx.Dereference(o)
+			if err != nil {
+				return
+			}
+			t, err := model.Text(o)
+			if err != nil {
+				return "", "", err
+			}
+			parts = append([]string{t}, parts...)
+		}
+
+		// The field type is inheritable.
+		if ft := d.NameEntry("FT"); ft != nil && typ == "" {
+			typ = *ft
+		}
+
+		d, err = ctx.DereferenceDict(d["Parent"])
+		if err != nil {
+			return
+		}
+	}
+	return strings.Join(parts, "."), typ, nil
+}
+
+// walkFields calls fn for each field dictionary of the AcroForm field tree
+// with the fully qualified field name. Widget annotations without a
+// partial field name are skipped.
+func walkFields(ctx *model.Context, fn func(name string, d types.Dict) error) error {
+	form, err := acroForm(ctx)
+	if err != nil || form == nil {
+		return err
+	}
+
+	fields, err := ctx.DereferenceArray(form["Fields"])
+	if err != nil {
+		return err
+	}
+	return walkFieldKids(ctx, fields, "", 0, fn)
+}
+
+func walkFieldKids(ctx *model.Context, kids types.Array, prefix string, depth int, fn func(name string, d types.Dict) error) error {
+	// Limit the depth to protect against cyclic references.
+	if depth >= 32 {
+		return nil
+	}
+
+	for _, o := range kids {
+		d, err := ctx.DereferenceDict(o)
+		if err != nil {
+			return err
+		} else if d == nil {
+			continue
+		}
+
+		t, ok := d.Find("T")
+		if !ok {
+			continue
+		}
+		t, err = ctx.Dereference(t)
+		if err != nil {
+			return err
+		}
+		partial, err := model.Text(t)
+		if err != nil {
+			return err
+		}
+
+		name := partial
+		if prefix != "" {
+			name = prefix + "." + partial
+		}
+
+		err = fn(name, d)
+		if err != nil {
+			return err
+		}
+
+		children, err := ctx.DereferenceArray(d["Kids"])
+		if err != nil {
+			return err
+		}
+		err = walkFieldKids(ctx, children, name, depth+1, fn)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// textString returns the PDF text string of s. Strings with characters
+// outside of printable ASCII are encoded as UTF-16BE hex string.
+func textString(s string) types.Object {
+	for _, r := range s {
+		if r < 0x20 || r > 0x7e {
+			return types.NewHexLiteral([]byte(types.EncodeUTF16String(s)))
+		}
+	}
+	escaped, err := types.Escape(s)
+	if err != nil {
+		return types.NewHexLiteral([]byte(types.EncodeUTF16String(s)))
+	}
+	return types.StringLiteral(*escaped)
+}
+
+// newFormXObject adds a new form XObject with the content and the
+// resources to the document. It is sized w by h user space units.
+func newFormXObject(ctx *model.Context, w, h float64, content string, resources types.Dict) (types.IndirectRef, error) {
+	sd, err := ctx.NewStreamDictForBuf([]byte(content))
+	if err != nil {
+		return types.IndirectRef{}, err
+	}
+	sd.InsertName("Type", "XObject")
+	sd.InsertName("Subtype", "Form")
+	sd.Insert("BBox", types.NewNumberArray(0, 0, w, h))
+	if resources != nil {
+		sd.Insert("Resources", resources)
+	}
+	err = sd.Encode()
+	if err != nil {
+		return types.IndirectRef{}, err
+	}
+	ref, err := ctx.IndRefForNewObject(*sd)
+	if err != nil {
+		return types.IndirectRef{}, err
+	}
+	return *ref, nil
+}
+
+// readViewports returns the visible regions of the pages of the PDF file.
+func readViewports(pdfFile, password string) ([]Rect, error) {
+	ctx, err := readPdfcpuContext(pdfFile, password)
+	if err != nil {
+		return nil, err
+	}
+
+	viewports := make([]Rect, ctx.PageCount)
+	for page := 1; page <= ctx.PageCount; page++ {
+		_, _, inh, err := ctx.PageDict(page, false)
+		if err != nil {
+			return nil, err
+		}
+
+		viewport := inh.MediaBox
+		if inh.CropBox != nil {
+			viewport = inh.CropBox
+		}
+		viewports[page-1] = rectFromPdfcpu(viewport)
+	}
+	return viewports, nil
+}
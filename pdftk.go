@@ -0,0 +1,189 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+)
+
+// pdftkBackend fills the form with the pdftk utility by passing
+// the form values as fdf data file.
+type pdftkBackend struct{}
+
+func (pdftkBackend) Fill(ctx context.Context, tmpDir string, form Form, formPDFFile, outputFile string, opts Options) error {
+	// Check if the pdftk utility exists.
+	err := checkPdftk()
+	if err != nil {
+		return err
+	}
+
+	// Map bool values to the export values of checkboxes.
+	if hasBoolValues(form) {
+		fields, err := dumpFields(ctx, tmpDir, formPDFFile, opts.InputPassword)
+		if err != nil {
+			return fmt.Errorf("failed to read form fields: %w", err)
+		}
+		form = resolveButtonValues(form, fields)
+	}
+
+	// Create the fdf data file.
+	fdfFile := filepath.Clean(tmpDir + "/data.fdf")
+	err = createFdfFile(form, fdfFile, opts.Encoding)
+	if err != nil {
+		return fmt.Errorf("failed to create fdf form data file: %w", err)
+	}
+
+	// Create the pdftk command line arguments.
+	args := pdftkInput(formPDFFile, opts.InputPassword)
+	args = append(args,
+		"fill_form", fdfFile,
+		"output", outputFile,
+	)
+	args = append(args, opts.pdftkFillFlags()...)
+
+	// Run the pdftk utility.
+	return runPdftk(ctx, tmpDir, args...)
+}
+
+// pdftkFillFlags returns the output flags of pdftk's fill_form operation.
+func (o Options) pdftkFillFlags() (flags []string) {
+	// If the user specified to flatten the output PDF we append the related parameter.
+	if o.Flatten {
+		flags = append(flags, "flatten")
+	} else if o.NeedAppearances {
+		flags = append(flags, "need_appearances")
+	}
+	if o.DropXFA {
+		flags = append(flags, "drop_xfa")
+	}
+	return
+}
+
+// PdftkEnv is the environment variable overriding the path of the pdftk binary.
+const PdftkEnv = "FILLPDF_PDFTK"
+
+// pdftkNames are the binary names searched within the PATH in order.
+// The .exe extension is added on Windows.
+var pdftkNames = []string{"pdftk", "pdftk-java"}
+
+var (
+	pdftkPathMutex sync.RWMutex
+	pdftkPath      string
+)
+
+// SetPdftkPath sets the path of the pdftk binary for hosts with a
+// non-standard installation. An empty path restores the lookup.
+// The binary is looked up in the following order: the path set by
+// SetPdftkPath, the FILLPDF_PDFTK environment variable, the
+// names pdftk and pdftk-java within the PATH and on Windows the
+// default install directory of PDFtk Server.
+func SetPdftkPath(path string) {
+	pdftkPathMutex.Lock()
+	pdftkPath = path
+	pdftkPathMutex.Unlock()
+}
+
+// pdftkBinary returns the path of the pdftk binary.
+func pdftkBinary() (string, error) {
+	pdftkPathMutex.RLock()
+	path := pdftkPath
+	pdftkPathMutex.RUnlock()
+
+	if path == "" {
+		path = os.Getenv(PdftkEnv)
+	}
+	if path != "" {
+		path, err := exec.LookPath(path)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrPdftkNotFound, err)
+		}
+		return path, nil
+	}
+
+	for _, name := range pdftkNames {
+		path, err := exec.LookPath(name)
+		if err == nil {
+			return path, nil
+		}
+	}
+	return lookInstallPath(pdftkInstallPaths(), ErrPdftkNotFound)
+}
+
+// checkPdftk returns an error if the pdftk utility is not installed.
+func checkPdftk() error {
+	_, err := pdftkBinary()
+	return err
+}
+
+// pdftkInput returns the pdftk input arguments for the PDF file.
+// The password is passed if set.
+func pdftkInput(pdfFile, password string) []string {
+	if password == "" {
+		return []string{pdfFile}
+	}
+	return []string{pdfFile, "input_pw", password}
+}
+
+// runPdftk runs the pdftk utility with the working directory dir.
+func runPdftk(ctx context.Context, dir string, args ...string) error {
+	_, err := runPdftkOutput(ctx, dir, args...)
+	return err
+}
+
+// runPdftkOutput is like runPdftk, but returns the standard output.
+func runPdftkOutput(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	bin, err := pdftkBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := runCommandOutputInPath(ctx, dir, bin, args...)
+	if err != nil {
+		return nil, fmt.Errorf("pdftk error: %w", err)
+	}
+	return out, nil
+}
+
+// pdftkCat concatenates the input PDF files to the output file.
+func pdftkCat(ctx context.Context, dir string, inputFiles []string, outputFile string) error {
+	args := append([]string{}, inputFiles...)
+	args = append(args, "cat", "output", outputFile)
+	return runPdftk(ctx, dir, args...)
+}
+
+func createFdfFile(form Form, path string, enc Encoding) (err error) {
+	// Create the file.
+	file, err := createFile(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		cerr := file.Close()
+		if err == nil {
+			err = cerr
+		}
+	}()
+
+	return writeFdf(file, form, enc)
+}
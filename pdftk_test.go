@@ -0,0 +1,116 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// writeExecutable creates an empty executable named name within dir.
+// The .exe extension is added on Windows.
+func writeExecutable(t *testing.T, dir, name string) string {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	path := filepath.Join(dir, name)
+	err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLookInstallPath(t *testing.T) {
+	dir := t.TempDir()
+	first := writeExecutable(t, dir, "first")
+	second := writeExecutable(t, dir, "second")
+	missing := filepath.Join(dir, "missing")
+	notFound := errors.New("not found")
+
+	tests := []struct {
+		name  string
+		paths []string
+		want  string
+		err   error
+	}{
+		{name: "no paths", paths: nil, err: notFound},
+		{name: "missing", paths: []string{missing}, err: notFound},
+		{name: "first", paths: []string{first, second}, want: first},
+		{name: "skip missing", paths: []string{missing, second}, want: second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := lookInstallPath(tt.paths, notFound)
+			if err != tt.err {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			} else if got != tt.want {
+				t.Fatalf("got path %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPdftkBinary(t *testing.T) {
+	dir := t.TempDir()
+	pathDir := filepath.Join(dir, "path")
+	err := os.Mkdir(pathDir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	custom := writeExecutable(t, dir, "custom-pdftk")
+	env := writeExecutable(t, dir, "env-pdftk")
+	java := writeExecutable(t, pathDir, "pdftk-java")
+	missing := filepath.Join(dir, "missing")
+
+	tests := []struct {
+		name string
+		set  string
+		env  string
+		want string
+		err  error
+	}{
+		{name: "set path", set: custom, want: custom},
+		{name: "set path overrides env", set: custom, env: env, want: custom},
+		{name: "env", env: env, want: env},
+		{name: "missing set path", set: missing, err: ErrPdftkNotFound},
+		{name: "missing env", env: missing, err: ErrPdftkNotFound},
+		{name: "path lookup", want: java},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PATH", pathDir)
+			t.Setenv(PdftkEnv, tt.env)
+			SetPdftkPath(tt.set)
+			defer SetPdftkPath("")
+
+			got, err := pdftkBinary()
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			} else if got != tt.want {
+				t.Fatalf("got path %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+func checkQpdf() error {
+	_, err := exec.LookPath("qpdf")
+	if err != nil {
+		return ErrQpdfNotFound
+	}
+	return nil
+}
+
+// runQpdf runs qpdf within the directory. Warnings do not fail the command.
+func runQpdf(ctx context.Context, dir string, args ...string) error {
+	args = append([]string{"--warning-exit-0"}, args...)
+	err := runCommandInPath(ctx, dir, "qpdf", args...)
+	if err != nil {
+		return fmt.Errorf("qpdf error: %w", err)
+	}
+	return nil
+}
+
+// linearizePass returns a pass linearizing the input file with qpdf.
+// The password is required for encrypted input files and the
+// encryption is preserved.
+func linearizePass(password string) pass {
+	return func(ctx context.Context, tmpDir, inputFile, outputFile string) error {
+		err := checkQpdf()
+		if err != nil {
+			return err
+		}
+
+		var args []string
+		if password != "" {
+			args = append(args, "--password="+password)
+		}
+		args = append(args, "--linearize", inputFile, outputFile)
+
+		err = runQpdf(ctx, tmpDir, args...)
+		if err != nil {
+			return fmt.Errorf("failed to linearize PDF: %w", err)
+		}
+		return nil
+	}
+}
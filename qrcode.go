@@ -0,0 +1,94 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"fmt"
+
+	"github.com/boombuler/barcode/qr"
+)
+
+// qrQuietZone is the width of the blank margin around QR codes in modules.
+const qrQuietZone = 4
+
+// qrModuleSize is the size of a rendered QR code module in pixels.
+const qrModuleSize = 8
+
+// QRCode is a form value placing a QR code into the rectangle of a
+// form field, typically an image push button. The QR code is generated
+// in-process and stamped onto the page like an Image value.
+type QRCode struct {
+	// Content is the encoded text, e.g. a verification URL or document ID.
+	Content string
+	// Level is the error correction level: L, M, Q or H. Defaults to M.
+	Level string
+}
+
+// String returns an empty string. QR codes have no text value.
+func (QRCode) String() string {
+	return ""
+}
+
+// QRCodeStamp places a QR code at fixed page coordinates.
+type QRCodeStamp struct {
+	QRCode
+	// Page is the page number starting with 1.
+	Page int
+	// Rect is the rectangle in PDF user space units the
+	// QR code is fitted into.
+	Rect Rect
+}
+
+// encodeImage returns the QR code as PNG image.
+// QR codes are square regardless of the rectangle.
+func (q QRCode) encodeImage(Rect) ([]byte, error) {
+	var level qr.ErrorCorrectionLevel
+	switch q.Level {
+	case "L":
+		level = qr.L
+	case "", "M":
+		level = qr.M
+	case "Q":
+		level = qr.Q
+	case "H":
+		level = qr.H
+	default:
+		return nil, fmt.Errorf("invalid QR code error correction level: '%s'", q.Level)
+	}
+
+	code, err := qr.Encode(q.Content, level, qr.Auto)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode QR code: %w", err)
+	}
+	return barcodePNG(code, qrModuleSize, qrModuleSize, qrQuietZone, qrQuietZone)
+}
+
+// qrCodeStamps returns the image stamps of the QR codes placed at fixed
+// page coordinates. The viewports are the visible regions of the pages.
+func qrCodeStamps(codes []QRCodeStamp, viewports []Rect) ([]imageStamp, error) {
+	stamps := make([]imageStamp, len(codes))
+	for i, c := range codes {
+		s, err := fixedStamp(c.QRCode, c.Page, c.Rect, viewports)
+		if err != nil {
+			return nil, fmt.Errorf("QR code '%s': %w", c.Content, err)
+		}
+		stamps[i] = s
+	}
+	return stamps, nil
+}
@@ -0,0 +1,133 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// RadioValue is a form value selecting the radio button of a radio group
+// by its export value. Unlike plain strings, the value is validated
+// against the export values of the group's radio buttons before filling
+// and a RadioValueError is returned if none matches. An empty value or
+// "Off" clears the selection.
+type RadioValue string
+
+// String returns the export value.
+func (r RadioValue) String() string {
+	return string(r)
+}
+
+// SetRadio selects the radio button with the export value of the radio group.
+func (f Form) SetRadio(group, value string) {
+	f[group] = RadioValue(value)
+}
+
+// radioValues returns the radio values of the form.
+func radioValues(form Form) map[string]string {
+	values := make(map[string]string)
+	for key, value := range form {
+		if r, ok := value.(RadioValue); ok && r != "" && r != buttonOffState {
+			values[key] = string(r)
+		}
+	}
+	return values
+}
+
+// checkRadioValues checks the radio values against the export values
+// of the radio buttons of their groups within the form PDF file.
+func checkRadioValues(values map[string]string, pdfFile, password string) error {
+	ctx, err := readPdfcpuContext(pdfFile, password)
+	if err != nil {
+		return err
+	}
+	return checkRadioValuesContext(ctx, values)
+}
+
+// checkRadioValuesContext is like checkRadioValues, but checks the
+// radio groups of the pdfcpu context.
+func checkRadioValuesContext(ctx *model.Context, values map[string]string) error {
+	options := make(map[string][]string, len(values))
+	err := walkFields(ctx, func(name string, d types.Dict) error {
+		if _, ok := values[name]; !ok {
+			return nil
+		}
+
+		flags, err := inheritedFlags(ctx, d)
+		if err != nil {
+			return err
+		} else if flags&flagRadio == 0 {
+			return fmt.Errorf("field is not a radio group: '%s'", name)
+		}
+
+		options[name], err = radioStates(ctx, d)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for group, value := range values {
+		opts, ok := options[group]
+		if !ok {
+			return fmt.Errorf("radio group does not exist: '%s'", group)
+		}
+		i := sort.SearchStrings(opts, value)
+		if i == len(opts) || opts[i] != value {
+			return &RadioValueError{Group: group, Value: value, Options: opts}
+		}
+	}
+	return nil
+}
+
+// radioStates returns the sorted export values of the radio buttons of
+// the group, which are the names of their on appearance states.
+func radioStates(ctx *model.Context, d types.Dict) ([]string, error) {
+	widgets, err := fieldWidgets(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
+	var states []string
+	seen := make(map[string]bool)
+	for _, w := range widgets {
+		ap, err := ctx.DereferenceDict(w["AP"])
+		if err != nil {
+			return nil, err
+		} else if ap == nil {
+			continue
+		}
+		n, err := ctx.DereferenceDict(ap["N"])
+		if err != nil {
+			return nil, err
+		}
+		for state := range n {
+			if state != buttonOffState && !seen[state] {
+				seen[state] = true
+				states = append(states, state)
+			}
+		}
+	}
+	sort.Strings(states)
+	return states, nil
+}
@@ -0,0 +1,67 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// readOnlyPass returns a pass setting the ReadOnly flag of the filled fields,
+// so viewers prevent editing while the values stay extractable.
+func readOnlyPass(form Form) pass {
+	return func(ctx context.Context, tmpDir, inputFile, outputFile string) error {
+		err := ctx.Err()
+		if err != nil {
+			return err
+		}
+
+		err = setReadOnly(inputFile, outputFile, form)
+		if err != nil {
+			return fmt.Errorf("failed to set fields read-only: %w", err)
+		}
+		return nil
+	}
+}
+
+func setReadOnly(inputFile, outputFile string, form Form) error {
+	ctx, err := readPdfcpuContext(inputFile, "")
+	if err != nil {
+		return err
+	}
+
+	err = walkFields(ctx, func(name string, d types.Dict) error {
+		if _, ok := form[name]; !ok {
+			return nil
+		}
+		flags, err := inheritedFlags(ctx, d)
+		if err != nil {
+			return err
+		}
+		d["Ff"] = types.Integer(flags | flagReadOnly)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return writePdfcpuContext(ctx, outputFile)
+}
@@ -0,0 +1,169 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// RemoteBackend fills the forms with a remote fill service, e.g. a pool of
+// workers serving the fillpdfhttp handler. The form PDF and the form values
+// are sent as multipart/form-data POST request with the parts:
+//
+//	template  the form PDF file
+//	data      the form values as JSON object
+//	options   the fill options as JSON object
+//
+// The service responds with the filled PDF. Values are sent as strings,
+// except bools and string slices. Rich text values are sent as plain text.
+type RemoteBackend struct {
+	// URL is the fill endpoint of the service.
+	URL string
+	// Client sends the requests. Defaults to http.DefaultClient if nil.
+	Client *http.Client
+	// Header is added to each request, e.g. for authorization.
+	Header http.Header
+}
+
+// remoteOptions are the fill options sent to the remote service.
+type remoteOptions struct {
+	Flatten         bool   `json:"flatten"`
+	NeedAppearances bool   `json:"needAppearances,omitempty"`
+	DropXFA         bool   `json:"dropXFA,omitempty"`
+	InputPassword   string `json:"inputPassword,omitempty"`
+	Encoding        string `json:"encoding,omitempty"`
+}
+
+// Fill sends the form PDF and the form values to the remote service
+// and writes the filled PDF of the response to the output file.
+func (b *RemoteBackend) Fill(ctx context.Context, tmpDir string, form Form, formPDFFile, outputFile string, opts Options) error {
+	body, contentType, err := remoteRequestBody(form, formPDFFile, opts)
+	if err != nil {
+		return fmt.Errorf("failed to create remote fill request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.URL, body)
+	if err != nil {
+		return fmt.Errorf("failed to create remote fill request: %w", err)
+	}
+	for key, values := range b.Header {
+		for _, v := range values {
+			req.Header.Add(key, v)
+		}
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", "application/pdf")
+
+	client := b.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("remote fill request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("remote fill failed: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	err = writeFile(outputFile, resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to write remote fill response: %w", err)
+	}
+	return nil
+}
+
+// remoteRequestBody returns the multipart request body and its content type.
+func remoteRequestBody(form Form, formPDFFile string, opts Options) (io.Reader, string, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	// Add the form PDF file.
+	in, err := os.Open(formPDFFile)
+	if err != nil {
+		return nil, "", err
+	}
+	defer in.Close()
+
+	part, err := w.CreateFormFile("template", "form.pdf")
+	if err != nil {
+		return nil, "", err
+	}
+	_, err = io.Copy(part, in)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Add the form values.
+	data := make(map[string]interface{}, len(form))
+	for key, value := range form {
+		switch v := value.(type) {
+		case bool, []string:
+			data[key] = v
+		default:
+			data[key] = formatValue(v)
+		}
+	}
+	err = writeJSONField(w, "data", data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Add the options.
+	ro := remoteOptions{
+		Flatten:         opts.Flatten,
+		NeedAppearances: opts.NeedAppearances,
+		DropXFA:         opts.DropXFA,
+		InputPassword:   opts.InputPassword,
+	}
+	if opts.Encoding == EncodingLatin1 {
+		ro.Encoding = "latin1"
+	}
+	err = writeJSONField(w, "options", ro)
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return nil, "", err
+	}
+	return &buf, w.FormDataContentType(), nil
+}
+
+func writeJSONField(w *multipart.Writer, name string, v interface{}) error {
+	part, err := w.CreateFormField(name)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(part).Encode(v)
+}
@@ -0,0 +1,399 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// inheritableFieldKeys are the field attributes inherited from the parent fields.
+var inheritableFieldKeys = []string{"FT", "Ff", "V", "DV", "DA", "Q"}
+
+// RenameFields renames the fields of the input PDF file and writes the
+// result to the destination file. The names map the old fully qualified
+// field names to the new ones, e.g. "address.city" to "buyer.address.city".
+// Missing parent fields are created and inherited attributes are kept.
+// This allows merging copies of the same form PDF without colliding fields.
+// All old names refer to the fields before renaming. An UnknownFieldsError
+// is returned if a field does not exist.
+// An existing destination file is replaced. No external utility is required.
+func RenameFields(inputPDFFile, destPDFFile string, names map[string]string) error {
+	return RenameFieldsContext(context.Background(), inputPDFFile, destPDFFile, names)
+}
+
+// RenameFieldsContext is like RenameFields, but checks the context
+// for cancellation before processing.
+func RenameFieldsContext(ctx context.Context, inputPDFFile, destPDFFile string, names map[string]string) (err error) {
+	// Get the absolute paths.
+	destPDFFile, err = filepath.Abs(destPDFFile)
+	if err != nil {
+		return fmt.Errorf("failed to create the absolute path: %w", err)
+	}
+	inputs, err := absExistingFiles([]string{inputPDFFile})
+	if err != nil {
+		return err
+	}
+
+	err = ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	// Create a temporary directory and remove it on defer again.
+	tmpDir, err := createTempDir("")
+	if err != nil {
+		return err
+	}
+	defer removeTempDir(tmpDir)
+
+	outputFile := filepath.Join(tmpDir, "output.pdf")
+	err = renameFields(inputs[0], outputFile, names)
+	if err != nil {
+		return err
+	}
+
+	return writeDestFile(outputFile, destPDFFile, true)
+}
+
+// fieldNode is a field of the AcroForm field tree.
+type fieldNode struct {
+	// obj is the entry of the parent's Kids or of the form's Fields.
+	obj types.Object
+	d   types.Dict
+	// parent is nil for top-level fields.
+	parent types.Dict
+}
+
+func renameFields(inputFile, outputFile string, names map[string]string) error {
+	ctx, err := readPdfcpuContext(inputFile, "")
+	if err != nil {
+		return err
+	}
+
+	form, err := acroForm(ctx)
+	if err != nil {
+		return err
+	}
+	nodes := make(map[string]*fieldNode)
+	if form != nil {
+		err = collectFieldNodes(ctx, form, nodes)
+		if err != nil {
+			return fmt.Errorf("failed to read form fields: %w", err)
+		}
+	}
+
+	err = checkFieldNames(names, nodes)
+	if err != nil {
+		return err
+	}
+
+	olds := make([]string, 0, len(names))
+	for old := range names {
+		olds = append(olds, old)
+	}
+	sort.Strings(olds)
+
+	for _, old := range olds {
+		err = renameField(ctx, form, nodes[old], old, names[old])
+		if err != nil {
+			return fmt.Errorf("failed to rename field '%s': %w", old, err)
+		}
+	}
+
+	return writePdfcpuContext(ctx, outputFile)
+}
+
+// checkFieldNames checks that the fields to rename exist and that
+// the new names are valid and do not collide.
+func checkFieldNames(names map[string]string, nodes map[string]*fieldNode) error {
+	var unknown []string
+	targets := make(map[string]string, len(names))
+	for old, name := range names {
+		if nodes[old] == nil {
+			unknown = append(unknown, old)
+			continue
+		}
+
+		if name == "" || strings.Contains("."+name+".", "..") {
+			return fmt.Errorf("invalid field name: '%s'", name)
+		} else if other, ok := targets[name]; ok {
+			return fmt.Errorf("fields '%s' and '%s' are both renamed to '%s'", other, old, name)
+		} else if _, ok := nodes[name]; ok {
+			if _, renamed := names[name]; !renamed {
+				return fmt.Errorf("field '%s' already exists", name)
+			}
+		}
+		targets[name] = old
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return &UnknownFieldsError{Keys: unknown}
+	}
+	return nil
+}
+
+// collectFieldNodes adds the fields of the AcroForm field tree
+// mapped by their fully qualified names.
+func collectFieldNodes(ctx *model.Context, form types.Dict, nodes map[string]*fieldNode) error {
+	fields, err := ctx.DereferenceArray(form["Fields"])
+	if err != nil {
+		return err
+	}
+	return collectFieldKids(ctx, fields, "", nil, 0, nodes)
+}
+
+func collectFieldKids(ctx *model.Context, kids types.Array, prefix string, parent types.Dict, depth int, nodes map[string]*fieldNode) error {
+	// Limit the depth to protect against cyclic references.
+	if depth >= 32 {
+		return nil
+	}
+
+	for _, o := range kids {
+		d, err := ctx.DereferenceDict(o)
+		if err != nil {
+			return err
+		} else if d == nil || d["T"] == nil {
+			continue
+		}
+		partial, err := partialName(ctx, d)
+		if err != nil {
+			return err
+		}
+
+		name := partial
+		if prefix != "" {
+			name = prefix + "." + partial
+		}
+		nodes[name] = &fieldNode{obj: o, d: d, parent: parent}
+
+		children, err := ctx.DereferenceArray(d["Kids"])
+		if err != nil {
+			return err
+		}
+		err = collectFieldKids(ctx, children, name, d, depth+1, nodes)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// partialName returns the partial field name of the field dictionary.
+func partialName(ctx *model.Context, d types.Dict) (string, error) {
+	t, err := ctx.Dereference(d["T"])
+	if err != nil {
+		return "", err
+	}
+	return model.Text(t)
+}
+
+// splitFieldName splits the fully qualified field name into
+// the name of the parent field and the partial name.
+func splitFieldName(name string) (parent, partial string) {
+	i := strings.LastIndexByte(name, '.')
+	if i < 0 {
+		return "", name
+	}
+	return name[:i], name[i+1:]
+}
+
+// renameField sets the partial name of the field and moves it
+// to the new parent field if required.
+func renameField(ctx *model.Context, form types.Dict, n *fieldNode, oldName, newName string) error {
+	oldParent, _ := splitFieldName(oldName)
+	newParent, partial := splitFieldName(newName)
+
+	n.d["T"] = textString(partial)
+	if oldParent == newParent {
+		return nil
+	}
+
+	ref, ok := n.obj.(types.IndirectRef)
+	if !ok {
+		return fmt.Errorf("field is no indirect object")
+	}
+
+	err := inheritFieldAttributes(ctx, n.d)
+	if err != nil {
+		return err
+	}
+
+	// Detach the field from its parent.
+	owner, key := form, "Fields"
+	if n.parent != nil {
+		owner, key = n.parent, "Kids"
+	}
+	kids, err := ctx.DereferenceArray(owner[key])
+	if err != nil {
+		return err
+	}
+	rest := make(types.Array, 0, len(kids))
+	for _, o := range kids {
+		if r, ok := o.(types.IndirectRef); !ok || r.ObjectNumber != ref.ObjectNumber {
+			rest = append(rest, o)
+		}
+	}
+	owner[key] = rest
+
+	// Attach the field to its new parent.
+	parent, parentRef, err := ensureParentField(ctx, form, newParent)
+	if err != nil {
+		return err
+	}
+	owner, key = form, "Fields"
+	if parent != nil {
+		owner, key = parent, "Kids"
+		n.d["Parent"] = *parentRef
+	} else {
+		n.d.Delete("Parent")
+	}
+	kids, err = ctx.DereferenceArray(owner[key])
+	if err != nil {
+		return err
+	}
+	owner[key] = append(kids, ref)
+	n.parent = parent
+	return nil
+}
+
+// inheritFieldAttributes copies the attributes inherited from the
+// parent fields into the field dictionary.
+func inheritFieldAttributes(ctx *model.Context, d types.Dict) error {
+	p, err := ctx.DereferenceDict(d["Parent"])
+	if err != nil {
+		return err
+	}
+
+	// Limit the depth to protect against cyclic references.
+	for i := 0; p != nil && i < 32; i++ {
+		for _, key := range inheritableFieldKeys {
+			if _, ok := d.Find(key); ok {
+				continue
+			}
+			if v, ok := p.Find(key); ok {
+				d[key] = v
+			}
+		}
+
+		p, err = ctx.DereferenceDict(p["Parent"])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureParentField returns the field with the fully qualified name,
+// which is created with its missing parents as non-terminal field.
+// Nil is returned for the empty name of the form's root.
+func ensureParentField(ctx *model.Context, form types.Dict, name string) (types.Dict, *types.IndirectRef, error) {
+	if name == "" {
+		return nil, nil, nil
+	}
+
+	var (
+		parent    types.Dict
+		parentRef *types.IndirectRef
+		path      string
+	)
+	owner, key := form, "Fields"
+
+Parts:
+	for _, part := range strings.Split(name, ".") {
+		if path != "" {
+			path += "."
+		}
+		path += part
+
+		kids, err := ctx.DereferenceArray(owner[key])
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, o := range kids {
+			d, err := ctx.DereferenceDict(o)
+			if err != nil {
+				return nil, nil, err
+			} else if d == nil || d["T"] == nil {
+				continue
+			}
+			partial, err := partialName(ctx, d)
+			if err != nil {
+				return nil, nil, err
+			} else if partial != part {
+				continue
+			}
+
+			r, ok := o.(types.IndirectRef)
+			terminal, err := isTerminalField(ctx, d)
+			if err != nil {
+				return nil, nil, err
+			} else if !ok || terminal {
+				return nil, nil, fmt.Errorf("field '%s' can not have child fields", path)
+			}
+			parent, parentRef = d, &r
+			owner, key = d, "Kids"
+			continue Parts
+		}
+
+		d := types.Dict{
+			"T":    textString(part),
+			"Kids": types.Array{},
+		}
+		if parentRef != nil {
+			d["Parent"] = *parentRef
+		}
+		r, err := ctx.IndRefForNewObject(d)
+		if err != nil {
+			return nil, nil, err
+		}
+		owner[key] = append(kids, *r)
+		parent, parentRef = d, r
+		owner, key = d, "Kids"
+	}
+	return parent, parentRef, nil
+}
+
+// isTerminalField returns whether the field dictionary is a terminal field,
+// which has widget annotations instead of child fields.
+func isTerminalField(ctx *model.Context, d types.Dict) (bool, error) {
+	if d["Rect"] != nil {
+		return true, nil
+	}
+	kids, err := ctx.DereferenceArray(d["Kids"])
+	if err != nil {
+		return false, err
+	}
+	for _, o := range kids {
+		kd, err := ctx.DereferenceDict(o)
+		if err != nil {
+			return false, err
+		} else if kd != nil && kd["T"] == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
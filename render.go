@@ -0,0 +1,145 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// ImageFormat is the image format of rendered pages.
+type ImageFormat int
+
+const (
+	// ImagePNG renders PNG images. This is the default.
+	ImagePNG ImageFormat = iota
+	// ImageJPEG renders JPEG images.
+	ImageJPEG
+)
+
+// RenderOptions alters the page rendering.
+type RenderOptions struct {
+	// DPI is the resolution in dots per inch. Defaults to 150 if zero.
+	DPI int
+	// Format is the image format. Defaults to PNG.
+	Format ImageFormat
+	// FirstPage is the first page to render. Defaults to the first page if zero.
+	FirstPage int
+	// LastPage is the last page to render. Defaults to the last page if zero.
+	LastPage int
+	// Password is the owner or user password of a password protected PDF.
+	Password string
+}
+
+// args returns the pdftoppm arguments.
+func (o RenderOptions) args() []string {
+	dpi := o.DPI
+	if dpi == 0 {
+		dpi = 150
+	}
+	args := []string{"-r", strconv.Itoa(dpi)}
+
+	switch o.Format {
+	case ImageJPEG:
+		args = append(args, "-jpeg")
+	default:
+		args = append(args, "-png")
+	}
+
+	if o.FirstPage > 0 {
+		args = append(args, "-f", strconv.Itoa(o.FirstPage))
+	}
+	if o.LastPage > 0 {
+		args = append(args, "-l", strconv.Itoa(o.LastPage))
+	}
+	if o.Password != "" {
+		args = append(args, "-opw", o.Password, "-upw", o.Password)
+	}
+	return args
+}
+
+// Render converts the pages of the PDF file into images,
+// e.g. to show a preview of a filled form.
+// The images are returned in page order.
+// The pdftoppm utility of poppler is required.
+func Render(pdfFile string, options ...RenderOptions) ([][]byte, error) {
+	return RenderContext(context.Background(), pdfFile, options...)
+}
+
+// RenderContext is like Render, but the context is used to cancel
+// the spawned external processes.
+func RenderContext(ctx context.Context, pdfFile string, options ...RenderOptions) ([][]byte, error) {
+	var opts RenderOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+
+	inputs, err := absExistingFiles([]string{pdfFile})
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkPdftoppm()
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a temporary directory and remove it on defer again.
+	tmpDir, err := createTempDir("")
+	if err != nil {
+		return nil, err
+	}
+	defer removeTempDir(tmpDir)
+
+	// The images are written to the temporary directory with zero padded
+	// page numbers, e.g. page-01.png.
+	args := append(opts.args(), inputs[0], filepath.Join(tmpDir, "page"))
+	err = runCommandInPath(ctx, tmpDir, "pdftoppm", args...)
+	if err != nil {
+		return nil, fmt.Errorf("pdftoppm error: %w", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(tmpDir, "page-*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	images := make([][]byte, len(files))
+	for i, f := range files {
+		images[i], err = ioutil.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read page image %d: %w", i+1, err)
+		}
+	}
+	return images, nil
+}
+
+func checkPdftoppm() error {
+	_, err := exec.LookPath("pdftoppm")
+	if err != nil {
+		return ErrPdftoppmNotFound
+	}
+	return nil
+}
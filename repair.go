@@ -0,0 +1,130 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+)
+
+// RepairPDF rewrites the damaged input PDF file to the destination file,
+// e.g. templates exported by buggy generators which make pdftk fail.
+// The cross-reference table is reconstructed with qpdf if it is installed
+// and with the pdfcpu library otherwise. An existing destination file is
+// replaced. The InputPassword option is used for password protected PDF
+// files and TempDir for the intermediate files, the other options are ignored.
+func RepairPDF(inputPDFFile, destPDFFile string, options ...Options) error {
+	return RepairPDFContext(context.Background(), inputPDFFile, destPDFFile, options...)
+}
+
+// RepairPDFContext is like RepairPDF, but the context is used to cancel
+// the spawned external processes.
+func RepairPDFContext(ctx context.Context, inputPDFFile, destPDFFile string, options ...Options) (err error) {
+	opts := getOptions(options)
+
+	// Get the absolute paths.
+	destPDFFile, err = filepath.Abs(destPDFFile)
+	if err != nil {
+		return fmt.Errorf("failed to create the absolute path: %w", err)
+	}
+	inputs, err := absExistingFiles([]string{inputPDFFile})
+	if err != nil {
+		return err
+	}
+
+	// Create a temporary directory and remove it on defer again.
+	tmpDir, err := createTempDir(opts.TempDir)
+	if err != nil {
+		return err
+	}
+	defer removeTempDir(tmpDir)
+
+	outputFile := filepath.Join(tmpDir, "output.pdf")
+	err = repairPDF(ctx, tmpDir, inputs[0], outputFile, opts.InputPassword)
+	if err != nil {
+		return err
+	}
+
+	return writeDestFile(outputFile, destPDFFile, true)
+}
+
+// repairFormFile repairs a copy of the form PDF file within tmpDir
+// and returns its path.
+func repairFormFile(ctx context.Context, tmpDir, formPDFFile, password string) (string, error) {
+	path := filepath.Join(tmpDir, "form-repaired.pdf")
+	err := repairPDF(ctx, tmpDir, formPDFFile, path, password)
+	if err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// repairPDF rewrites the input file with qpdf or pdfcpu as fallback.
+// The encryption is preserved.
+func repairPDF(ctx context.Context, tmpDir, inputFile, outputFile, password string) error {
+	if checkQpdf() == nil {
+		var args []string
+		if password != "" {
+			args = append(args, "--password="+password)
+		}
+		args = append(args, inputFile, outputFile)
+
+		err := runQpdf(ctx, tmpDir, args...)
+		if err != nil {
+			return fmt.Errorf("failed to repair PDF: %w", err)
+		}
+		return nil
+	}
+
+	// pdfcpu does not support cancellation, so check at least once.
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	err = pdfcpuRewrite(inputFile, outputFile, password)
+	if err != nil {
+		return fmt.Errorf("failed to repair PDF: pdfcpu error: %w", err)
+	}
+	return nil
+}
+
+// pdfcpuRewrite reads the input file without validation, which
+// reconstructs a damaged cross-reference table, and writes it again.
+func pdfcpuRewrite(inputFile, outputFile, password string) error {
+	in, err := os.Open(inputFile)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	ctx, err := api.ReadContext(in, pdfcpuReadConfig(password))
+	if err != nil {
+		return err
+	}
+	err = ctx.EnsurePageCount()
+	if err != nil {
+		return err
+	}
+	return writePdfcpuContext(ctx, outputFile)
+}
@@ -0,0 +1,103 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"sort"
+)
+
+// FillResult reports the outcome of a fill, e.g. to log the fill coverage.
+// All names are sorted.
+type FillResult struct {
+	// Set are the form keys which matched a field.
+	Set []string
+	// Ignored are the form keys without a matching field.
+	Ignored []string
+	// Empty are the fields of the form PDF which stay empty,
+	// because neither the form nor the form PDF provide a value.
+	Empty []string
+}
+
+// FillWithResult is like Fill, but additionally reports which form keys
+// were set or ignored and which fields stay empty. The fields are read
+// with pdftk, or with pdfcpu if the Pdfcpu backend is used.
+// The result is empty for XFA filling.
+func FillWithResult(form Form, formPDFFile, destPDFFile string, options ...Options) (*FillResult, error) {
+	return FillWithResultContext(context.Background(), form, formPDFFile, destPDFFile, options...)
+}
+
+// FillWithResultContext is like FillWithResult, but the context is used to cancel
+// the fill process and the spawned external processes.
+func FillWithResultContext(ctx context.Context, form Form, formPDFFile, destPDFFile string, options ...Options) (*FillResult, error) {
+	inputs, err := absExistingFiles([]string{formPDFFile})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &FillResult{}
+	err = fill(ctx, form, fileSource(inputs[0]), destPDFFile, nil, result, options...)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// newFillResult compares the form with the fields of the form PDF.
+func newFillResult(form Form, fields []Field) FillResult {
+	var r FillResult
+	names := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		names[f.Name] = true
+
+		if f.Flags.Pushbutton {
+			continue
+		} else if v, ok := form[f.Name]; ok && !isEmptyValue(v) {
+			continue
+		} else if !ok && f.Value != "" && f.Value != buttonOffState {
+			continue
+		}
+		r.Empty = append(r.Empty, f.Name)
+	}
+
+	for key := range form {
+		if names[key] {
+			r.Set = append(r.Set, key)
+		} else {
+			r.Ignored = append(r.Ignored, key)
+		}
+	}
+
+	sort.Strings(r.Set)
+	sort.Strings(r.Ignored)
+	sort.Strings(r.Empty)
+	return r
+}
+
+// isEmptyValue returns whether the form value leaves the field empty.
+func isEmptyValue(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return !v
+	case Image, SignatureImage, imageEncoder:
+		return false
+	}
+	s := formatValue(value)
+	return s == "" || s == buttonOffState
+}
@@ -0,0 +1,97 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"fmt"
+	"html"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+const richTextBodyStart = `<?xml version="1.0"?><body xmlns="http://www.w3.org/1999/xhtml" ` +
+	`xmlns:xfa="http://www.xfa.org/schema/xfa-data/1.0/" xfa:APIVersion="Acrobat:11.0.0" xfa:spec="2.0.2">`
+
+// RichText is a form value for text fields with the RichText flag.
+// Viewers supporting rich text show the rich text value, others the plain value.
+// Only the pdftk backend writes the rich text value.
+type RichText struct {
+	// Value is the plain text value.
+	Value string
+	// XHTML is the rich text body content, e.g. "<p>Hello <b>World</b></p>".
+	// If empty, a paragraph with the plain value styled by Style is generated.
+	XHTML string
+	// Style of the generated rich text.
+	Style TextStyle
+}
+
+// String returns the plain text value.
+func (r RichText) String() string {
+	return r.Value
+}
+
+// richValue returns the XHTML rich text value with the body envelope.
+func (r RichText) richValue() string {
+	body := r.XHTML
+	if body == "" {
+		body = "<p"
+		if style := r.Style.css(); style != "" {
+			body += ` style="` + html.EscapeString(style) + `"`
+		}
+		body += ">" + html.EscapeString(r.Value) + "</p>"
+	}
+	return richTextBodyStart + body + "</body>"
+}
+
+// TextStyle defines the style of text.
+type TextStyle struct {
+	// Font is the font family name.
+	Font string
+	// Size is the font size in points.
+	Size float64
+	// Color is the text color.
+	Color color.Color
+	// Bold text.
+	Bold bool
+	// Italic text.
+	Italic bool
+}
+
+// css returns the style as CSS declarations.
+func (s TextStyle) css() string {
+	var decl []string
+	if s.Font != "" {
+		decl = append(decl, "font-family:'"+s.Font+"'")
+	}
+	if s.Size > 0 {
+		decl = append(decl, "font-size:"+strconv.FormatFloat(s.Size, 'f', -1, 64)+"pt")
+	}
+	if s.Color != nil {
+		r, g, b, _ := s.Color.RGBA()
+		decl = append(decl, fmt.Sprintf("color:#%02x%02x%02x", r>>8, g>>8, b>>8))
+	}
+	if s.Bold {
+		decl = append(decl, "font-weight:bold")
+	}
+	if s.Italic {
+		decl = append(decl, "font-style:italic")
+	}
+	return strings.Join(decl, ";")
+}
@@ -0,0 +1,87 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"fmt"
+)
+
+// PageRotation sets the rotation of a page range.
+type PageRotation struct {
+	// Pages is the page range in pdftk syntax, e.g. "1", "2-5", "3-end"
+	// or "1-endodd". Defaults to all pages if empty.
+	Pages string
+	// Angle is the absolute page rotation in degrees: 0, 90, 180 or 270.
+	// The rotation is clockwise.
+	Angle int
+}
+
+// pdftkRotations maps the rotation angles to the absolute pdftk page rotations.
+var pdftkRotations = map[int]string{
+	0:   "north",
+	90:  "east",
+	180: "south",
+	270: "west",
+}
+
+// arg returns the page range argument with the rotation suffix.
+func (r PageRotation) arg() (string, error) {
+	angle := r.Angle % 360
+	if angle < 0 {
+		angle += 360
+	}
+	rot, ok := pdftkRotations[angle]
+	if !ok {
+		return "", fmt.Errorf("invalid rotation angle %d: must be a multiple of 90", r.Angle)
+	}
+
+	pages := r.Pages
+	if pages == "" {
+		pages = "1-end"
+	}
+	return pages + rot, nil
+}
+
+// rotatePass returns a pass rotating the page ranges with pdftk's
+// rotate operation. Pages without a rotation are kept unchanged.
+func rotatePass(rotations []PageRotation) pass {
+	return func(ctx context.Context, tmpDir, inputFile, outputFile string) error {
+		err := checkPdftk()
+		if err != nil {
+			return err
+		}
+
+		args := []string{inputFile, "rotate"}
+		for _, r := range rotations {
+			arg, err := r.arg()
+			if err != nil {
+				return err
+			}
+			args = append(args, arg)
+		}
+		args = append(args, "output", outputFile)
+
+		err = runPdftk(ctx, tmpDir, args...)
+		if err != nil {
+			return fmt.Errorf("failed to rotate pages: %w", err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,210 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// unsafeActions are the action types running scripts, opening external
+// files and web sites or sending and importing form data.
+var unsafeActions = map[string]bool{
+	"JavaScript": true,
+	"Launch":     true,
+	"URI":        true,
+	"GoToR":      true,
+	"GoToE":      true,
+	"SubmitForm": true,
+	"ImportData": true,
+}
+
+// Sanitize removes JavaScript, launch, URI and other actions referring to
+// external resources, embedded files, file attachment annotations and
+// reference XObjects of the input PDF file and writes the result to the
+// destination file, e.g. to distribute a filled PDF safely.
+// An existing destination file is replaced. No external utility is required.
+func Sanitize(inputPDFFile, destPDFFile string) error {
+	return SanitizeContext(context.Background(), inputPDFFile, destPDFFile)
+}
+
+// SanitizeContext is like Sanitize, but checks the context
+// for cancellation before processing.
+func SanitizeContext(ctx context.Context, inputPDFFile, destPDFFile string) (err error) {
+	// Get the absolute paths.
+	destPDFFile, err = filepath.Abs(destPDFFile)
+	if err != nil {
+		return fmt.Errorf("failed to create the absolute path: %w", err)
+	}
+	inputs, err := absExistingFiles([]string{inputPDFFile})
+	if err != nil {
+		return err
+	}
+
+	// Create a temporary directory and remove it on defer again.
+	tmpDir, err := createTempDir("")
+	if err != nil {
+		return err
+	}
+	defer removeTempDir(tmpDir)
+
+	outputFile := filepath.Join(tmpDir, "output.pdf")
+	err = sanitizePass(ctx, tmpDir, inputs[0], outputFile)
+	if err != nil {
+		return err
+	}
+
+	return writeDestFile(outputFile, destPDFFile, true)
+}
+
+func sanitizePass(ctx context.Context, tmpDir, inputFile, outputFile string) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	err = sanitize(inputFile, outputFile)
+	if err != nil {
+		return fmt.Errorf("failed to sanitize PDF: %w", err)
+	}
+	return nil
+}
+
+func sanitize(inputFile, outputFile string) error {
+	ctx, err := readPdfcpuContext(inputFile, "")
+	if err != nil {
+		return err
+	}
+
+	for _, name := range []string{"JavaScript", "EmbeddedFiles"} {
+		err = removeNameTree(ctx, name)
+		if err != nil {
+			return err
+		}
+	}
+	err = removeActions(ctx, unsafeActions)
+	if err != nil {
+		return err
+	}
+
+	// Remove the base URI and the associated files of the document.
+	root, err := ctx.Catalog()
+	if err != nil {
+		return err
+	}
+	root.Delete("URI")
+	err = ctx.DeleteDictEntry(root, "AF")
+	if err != nil {
+		return err
+	}
+
+	err = removeFileAttachments(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Reference XObjects import the content of external files.
+	for _, entry := range ctx.Table {
+		if entry == nil || entry.Free {
+			continue
+		}
+		if sd, ok := entry.Object.(types.StreamDict); ok {
+			err = ctx.DeleteDictEntry(sd.Dict, "Ref")
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return writePdfcpuContext(ctx, outputFile)
+}
+
+// removeFileAttachments removes the file attachment annotations
+// with their popups and embedded files from all pages.
+func removeFileAttachments(ctx *model.Context) error {
+	for page := 1; page <= ctx.PageCount; page++ {
+		d, _, _, err := ctx.PageDict(page, false)
+		if err != nil {
+			return err
+		}
+		annots, err := ctx.DereferenceArray(d["Annots"])
+		if err != nil {
+			return err
+		} else if len(annots) == 0 {
+			continue
+		}
+
+		// Collect the attachments with their popups first.
+		removed := make(map[int]bool)
+		for _, o := range annots {
+			a, err := ctx.DereferenceDict(o)
+			if err != nil {
+				return err
+			} else if !isFileAttachment(a) {
+				continue
+			}
+			if ref, ok := o.(types.IndirectRef); ok {
+				removed[ref.ObjectNumber.Value()] = true
+			}
+			if popup, ok := a["Popup"].(types.IndirectRef); ok {
+				removed[popup.ObjectNumber.Value()] = true
+			}
+
+			// The annotation is freed with its file specification only,
+			// a deep removal would free the page, too.
+			err = ctx.DeleteDictEntry(a, "FS")
+			if err != nil {
+				return err
+			}
+		}
+
+		kept := types.Array{}
+		for _, o := range annots {
+			ref, ok := o.(types.IndirectRef)
+			if !ok {
+				if a, _ := o.(types.Dict); !isFileAttachment(a) {
+					kept = append(kept, o)
+				}
+				continue
+			} else if !removed[ref.ObjectNumber.Value()] {
+				kept = append(kept, o)
+				continue
+			}
+			err = ctx.FreeObject(ref.ObjectNumber.Value())
+			if err != nil {
+				return err
+			}
+		}
+		if len(kept) > 0 {
+			d["Annots"] = kept
+		} else {
+			d.Delete("Annots")
+		}
+	}
+	return nil
+}
+
+func isFileAttachment(d types.Dict) bool {
+	st := d.NameEntry("Subtype")
+	return st != nil && *st == "FileAttachment"
+}
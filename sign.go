@@ -0,0 +1,277 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultSignatureField = "Signature1"
+
+	// signatureStyle is the name of the pyHanko stamp style
+	// of visible signatures.
+	signatureStyle = "fillpdf"
+)
+
+// Signature defines how a PDF is digitally signed.
+// A PAdES signature is applied with the pyHanko utility.
+type Signature struct {
+	// PKCS12File is the path to the .p12 or .pfx file containing
+	// the signing certificate and private key.
+	PKCS12File string
+	// Password decrypts the PKCS#12 file.
+	Password string
+	// Field is the name of the signature field. The field is created
+	// if it does not exist, invisibly unless the Appearance defines its
+	// position. Defaults to "Signature1".
+	Field string
+	// Reason for signing the document. Optional.
+	Reason string
+	// Location of the signer. Optional.
+	Location string
+	// TimestampURL is the URL of an RFC 3161 time stamping authority.
+	// If set, a trusted timestamp is embedded into the signature, so it
+	// remains verifiable after the certificate expired. Optional.
+	TimestampURL string
+	// LTV embeds the OCSP responses and CRLs of the certificate chain into
+	// the document security store, so the signature supports long-term
+	// validation. With a TimestampURL, a document timestamp is added, too.
+	LTV bool
+	// TrustRoots are the paths of PEM or DER encoded certificates trusted
+	// when validating the certificate chain for LTV in addition to the
+	// system's trust store. Optional.
+	TrustRoots []string
+	// Appearance renders a visible signature if set.
+	// Otherwise the signature is invisible.
+	Appearance *SignatureAppearance
+}
+
+// SignatureAppearance defines the visible signature. It shows the signer
+// name, the signing time and the reason and location, if set.
+type SignatureAppearance struct {
+	// Page is the page number starting with 1 of the new signature field.
+	// Defaults to the first page.
+	Page int
+	// Rect is the position of the new signature field on the page.
+	// If empty, the existing signature field is used.
+	Rect Rect
+	// Name is the shown signer name. Defaults to the name
+	// of the signing certificate.
+	Name string
+	// Image is the path of a PNG or PDF file shown as background,
+	// e.g. a scanned signature or a logo. Optional.
+	Image string
+}
+
+// fieldSpec returns the pyHanko field specification of the signature field.
+// New visible fields are described by their page and position.
+func (s *Signature) fieldSpec() string {
+	field := s.Field
+	if field == "" {
+		field = defaultSignatureField
+	}
+
+	a := s.Appearance
+	if a == nil || a.Rect.Width() <= 0 || a.Rect.Height() <= 0 {
+		return field
+	}
+	page := a.Page
+	if page < 1 {
+		page = 1
+	}
+	coords := make([]string, 4)
+	for i, c := range []float64{a.Rect.LLX, a.Rect.LLY, a.Rect.URX, a.Rect.URY} {
+		coords[i] = strconv.FormatFloat(c, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%d/%s/%s", page, strings.Join(coords, ","), field)
+}
+
+// stampStyle returns the pyHanko stamp style of the visible signature.
+func (s *Signature) stampStyle() (map[string]interface{}, error) {
+	// pyHanko interpolates the text, so percent signs are escaped.
+	escape := strings.NewReplacer("%", "%%").Replace
+
+	name := "%(signer)s"
+	if s.Appearance.Name != "" {
+		name = escape(s.Appearance.Name)
+	}
+	lines := []string{"Signed by: " + name, "Date: %(ts)s"}
+	if s.Reason != "" {
+		lines = append(lines, "Reason: "+escape(s.Reason))
+	}
+	if s.Location != "" {
+		lines = append(lines, "Location: "+escape(s.Location))
+	}
+
+	style := map[string]interface{}{
+		"type":       "text",
+		"stamp-text": strings.Join(lines, "\n"),
+	}
+	if s.Appearance.Image != "" {
+		image, err := filepath.Abs(s.Appearance.Image)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the absolute path: %w", err)
+		}
+		style["background"] = image
+	}
+	return style, nil
+}
+
+// writeConfig writes the pyHanko configuration file, which defines the
+// stamp style of visible signatures. JSON is a subset of YAML.
+func (s *Signature) writeConfig(path string) error {
+	style, err := s.stampStyle()
+	if err != nil {
+		return err
+	}
+	config := map[string]interface{}{
+		"stamp-styles": map[string]interface{}{signatureStyle: style},
+	}
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// Sign digitally signs the input PDF file and writes the signed PDF
+// to the destination file. An existing destination file is replaced.
+func Sign(inputPDFFile, destPDFFile string, sig Signature) error {
+	return SignContext(context.Background(), inputPDFFile, destPDFFile, sig)
+}
+
+// SignContext is like Sign, but the context is used to cancel
+// the spawned external processes.
+func SignContext(ctx context.Context, inputPDFFile, destPDFFile string, sig Signature) (err error) {
+	// Get the absolute paths.
+	destPDFFile, err = filepath.Abs(destPDFFile)
+	if err != nil {
+		return fmt.Errorf("failed to create the absolute path: %w", err)
+	}
+	inputs, err := absExistingFiles([]string{inputPDFFile})
+	if err != nil {
+		return err
+	}
+
+	// Create a temporary directory and remove it on defer again.
+	tmpDir, err := createTempDir("")
+	if err != nil {
+		return err
+	}
+	defer removeTempDir(tmpDir)
+
+	outputFile := filepath.Join(tmpDir, "output.pdf")
+	err = sig.pass(ctx, tmpDir, inputs[0], outputFile)
+	if err != nil {
+		return err
+	}
+
+	return writeDestFile(outputFile, destPDFFile, true)
+}
+
+// args returns the pyHanko command line arguments.
+// The password is read from the passfile and the
+// configuration file is passed if set.
+func (s *Signature) args(inputFile, outputFile, passFile, configFile string) ([]string, error) {
+	if s.PKCS12File == "" {
+		return nil, fmt.Errorf("signature requires a PKCS#12 file")
+	}
+	pkcs12File, err := filepath.Abs(s.PKCS12File)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the absolute path: %w", err)
+	}
+
+	var args []string
+	if configFile != "" {
+		args = append(args, "--config", configFile)
+	}
+	args = append(args, "sign", "addsig", "--use-pades", "--field", s.fieldSpec())
+	if s.Appearance != nil {
+		args = append(args, "--style-name", signatureStyle)
+	}
+	if s.Reason != "" {
+		args = append(args, "--reason", s.Reason)
+	}
+	if s.Location != "" {
+		args = append(args, "--location", s.Location)
+	}
+	if s.TimestampURL != "" {
+		args = append(args, "--timestamp-url", s.TimestampURL)
+	}
+	if s.LTV {
+		args = append(args, "--with-validation-info")
+		if s.TimestampURL != "" {
+			args = append(args, "--use-pades-lta")
+		}
+		for _, root := range s.TrustRoots {
+			root, err = filepath.Abs(root)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create the absolute path: %w", err)
+			}
+			args = append(args, "--trust", root)
+		}
+	}
+	args = append(args, "pkcs12", "--passfile", passFile, inputFile, outputFile, pkcs12File)
+	return args, nil
+}
+
+func (s *Signature) pass(ctx context.Context, tmpDir, inputFile, outputFile string) error {
+	// Check if the pyhanko utility exists.
+	_, err := exec.LookPath("pyhanko")
+	if err != nil {
+		return ErrPyhankoNotFound
+	}
+
+	// Don't pass the password as command line argument.
+	passFile := filepath.Join(tmpDir, "signature.pass")
+	err = ioutil.WriteFile(passFile, []byte(s.Password), 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write signature password file: %w", err)
+	}
+
+	// Visible signatures require a stamp style.
+	var configFile string
+	if s.Appearance != nil {
+		configFile = filepath.Join(tmpDir, "pyhanko.yml")
+		err = s.writeConfig(configFile)
+		if err != nil {
+			return fmt.Errorf("failed to write pyhanko config file: %w", err)
+		}
+	}
+
+	args, err := s.args(inputFile, outputFile, passFile, configFile)
+	if err != nil {
+		return err
+	}
+
+	err = runCommandInPath(ctx, tmpDir, "pyhanko", args...)
+	if err != nil {
+		return fmt.Errorf("pyhanko error: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// SignatureStatus is the validity of a signature.
+type SignatureStatus string
+
+const (
+	// SignatureValid marks authentic signatures with trusted certificates
+	// of documents which have not been modified after signing.
+	SignatureValid SignatureStatus = "valid"
+	// SignatureInvalid marks signatures failing the verification.
+	SignatureInvalid SignatureStatus = "invalid"
+	// SignatureUnknown marks signatures which could not be verified.
+	SignatureUnknown SignatureStatus = "unknown"
+)
+
+// SignatureInfo describes a verified signature.
+type SignatureInfo struct {
+	// Field is the name of the signature field.
+	Field string
+	// Signer is the identity of the signing certificate or
+	// the signer name of the signature if unavailable.
+	Signer string
+	// SigningTime is the claimed or timestamped signing time.
+	// It is zero if unavailable.
+	SigningTime time.Time
+	// WholeDocument reports whether the signature covers the whole
+	// document. Otherwise the document was changed after signing,
+	// e.g. by incremental updates.
+	WholeDocument bool
+	// Status is the validity of the signature.
+	Status SignatureStatus
+	// Reason explains the status, e.g. "document has been modified".
+	Reason string
+}
+
+// VerifySignatures verifies the digital signatures of the PDF file and
+// returns their details, e.g. to check incoming signed forms before
+// processing them. No signatures are returned for unsigned documents.
+// The certificates are verified against the root certificates of the
+// pdfcpu configuration directory. The InputPassword option is used for
+// password protected PDF files, the other options are ignored.
+func VerifySignatures(pdfFile string, options ...Options) ([]SignatureInfo, error) {
+	return VerifySignaturesContext(context.Background(), pdfFile, options...)
+}
+
+// VerifySignaturesContext is like VerifySignatures, but checks the
+// context for cancellation before processing.
+func VerifySignaturesContext(ctx context.Context, pdfFile string, options ...Options) ([]SignatureInfo, error) {
+	opts := getOptions(options)
+
+	err := ctx.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	inputs, err := absExistingFiles([]string{pdfFile})
+	if err != nil {
+		return nil, err
+	}
+
+	// pdfcpu fails for unsigned documents.
+	pdfCtx, err := readPdfcpuContext(inputs[0], opts.InputPassword)
+	if err != nil {
+		return nil, err
+	} else if len(pdfCtx.Signatures) == 0 && !pdfCtx.SignatureExist && !pdfCtx.AppendOnly {
+		return nil, nil
+	}
+
+	results, err := api.ValidateSignatures(inputs[0], true, pdfcpuConfig(opts.InputPassword))
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]SignatureInfo, 0, len(results))
+	for _, r := range results {
+		if !r.Signed {
+			continue
+		}
+		infos = append(infos, signatureInfo(r))
+	}
+	return infos, nil
+}
+
+func signatureInfo(r *model.SignatureValidationResult) SignatureInfo {
+	info := SignatureInfo{
+		Field:         r.Details.FieldName,
+		Signer:        r.Details.SignerIdentity,
+		SigningTime:   r.Details.SigningTime,
+		WholeDocument: r.DocModified != model.True,
+		Status:        SignatureUnknown,
+		Reason:        r.Reason.String(),
+	}
+	if info.Signer == "" {
+		info.Signer = r.Details.SignerName
+	}
+
+	switch r.Status {
+	case model.SignatureStatusValid:
+		info.Status = SignatureValid
+	case model.SignatureStatusInvalid:
+		info.Status = SignatureInvalid
+	}
+	if r.Reason == model.SignatureReasonInternal && len(r.Problems) > 0 {
+		info.Reason = r.Problems[0]
+	}
+	return info
+}
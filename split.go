@@ -0,0 +1,76 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+)
+
+// Split bursts the PDF file into single page documents.
+// The documents are returned in page order.
+func Split(pdfFile string) ([][]byte, error) {
+	return SplitContext(context.Background(), pdfFile)
+}
+
+// SplitContext is like Split, but the context is used to cancel
+// the spawned external processes.
+func SplitContext(ctx context.Context, pdfFile string) ([][]byte, error) {
+	inputs, err := absExistingFiles([]string{pdfFile})
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkPdftk()
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a temporary directory and remove it on defer again.
+	tmpDir, err := createTempDir("")
+	if err != nil {
+		return nil, err
+	}
+	defer removeTempDir(tmpDir)
+
+	// The pages are written to the temporary directory.
+	// pdftk also writes a doc_data.txt file into the working directory.
+	err = runPdftk(ctx, tmpDir, inputs[0], "burst", "output", filepath.Join(tmpDir, "page_%06d.pdf"))
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := filepath.Glob(filepath.Join(tmpDir, "page_*.pdf"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	pages := make([][]byte, len(files))
+	for i, f := range files {
+		pages[i], err = ioutil.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read page %d: %w", i+1, err)
+		}
+	}
+	return pages, nil
+}
@@ -0,0 +1,415 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// annotationRef is the reference of a structure element to an annotation.
+type annotationRef struct {
+	// elem is the structure element. It is zero for direct elements,
+	// which can not be referenced by the parent tree.
+	elem     types.IndirectRef
+	elemDict types.Dict
+	// kids is the K array holding the reference at index.
+	// It is nil if the K entry is the reference itself.
+	kids  types.Array
+	index int
+}
+
+// flattenTaggedPass flattens the form fields with pdfcpu, keeping the
+// logical structure of tagged PDFs intact.
+func flattenTaggedPass(ctx context.Context, tmpDir, inputFile, outputFile string) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	err = flattenTagged(inputFile, outputFile)
+	if err != nil {
+		return fmt.Errorf("failed to flatten form: %w", err)
+	}
+	return nil
+}
+
+// flattenTagged paints the appearances of the form field widgets into
+// the page contents and removes the form. The appearances are marked
+// content of the structure elements which referenced the widgets before.
+func flattenTagged(inputFile, outputFile string) error {
+	ctx, err := readPdfcpuContext(inputFile, "")
+	if err != nil {
+		return err
+	}
+
+	err = flattenTaggedContext(ctx)
+	if err != nil {
+		return err
+	}
+	return writePdfcpuContext(ctx, outputFile)
+}
+
+// flattenTaggedContext is like flattenTagged, but flattens the form
+// of the pdfcpu context.
+func flattenTaggedContext(ctx *model.Context) error {
+	root, err := ctx.Catalog()
+	if err != nil {
+		return err
+	}
+	str, err := ctx.DereferenceDict(root["StructTreeRoot"])
+	if err != nil {
+		return err
+	}
+	refs := make(map[int]annotationRef)
+	if str != nil {
+		err = walkStructElems(ctx, types.IndirectRef{}, str, refs, 0)
+		if err != nil {
+			return err
+		}
+	}
+
+	free := make(map[int]bool)
+	for page := 1; page <= ctx.PageCount; page++ {
+		err = flattenPageWidgets(ctx, page, str, refs, free)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Remove the form with its field dictionaries.
+	if ref, ok := root["AcroForm"].(types.IndirectRef); ok {
+		free[ref.ObjectNumber.Value()] = true
+	}
+	err = collectFieldRefs(ctx, root, free)
+	if err != nil {
+		return err
+	}
+	root.Delete("AcroForm")
+	root.Delete("NeedsRendering")
+	for objNr := range free {
+		err = ctx.FreeObject(objNr)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectFieldRefs adds the object numbers of the fields of the form.
+func collectFieldRefs(ctx *model.Context, root types.Dict, refs map[int]bool) error {
+	form, err := ctx.DereferenceDict(root["AcroForm"])
+	if err != nil || form == nil {
+		return err
+	}
+	return collectFieldObjects(ctx, form["Fields"], refs, 0)
+}
+
+func collectFieldObjects(ctx *model.Context, o types.Object, refs map[int]bool, depth int) error {
+	// Limit the depth to protect against cyclic references.
+	if depth >= 32 {
+		return nil
+	}
+
+	kids, err := ctx.DereferenceArray(o)
+	if err != nil {
+		return err
+	}
+	for _, k := range kids {
+		ref, ok := k.(types.IndirectRef)
+		if !ok || refs[ref.ObjectNumber.Value()] {
+			continue
+		}
+		refs[ref.ObjectNumber.Value()] = true
+
+		d, err := ctx.DereferenceDict(ref)
+		if err != nil {
+			return err
+		} else if d == nil {
+			continue
+		}
+		err = collectFieldObjects(ctx, d["Kids"], refs, depth+1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkStructElems collects the annotation references of the structure
+// element and its descendants mapped by the annotation object numbers.
+func walkStructElems(ctx *model.Context, ref types.IndirectRef, elem types.Dict, refs map[int]annotationRef, depth int) error {
+	// Limit the depth to protect against cyclic references.
+	if depth >= 64 {
+		return nil
+	}
+
+	k, err := ctx.Dereference(elem["K"])
+	if err != nil {
+		return err
+	}
+	kids, ok := k.(types.Array)
+	if !ok {
+		return walkStructKid(ctx, ref, elem, nil, 0, elem["K"], refs, depth)
+	}
+	for i, kid := range kids {
+		err = walkStructKid(ctx, ref, elem, kids, i, kid, refs, depth)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkStructKid(ctx *model.Context, ref types.IndirectRef, elem types.Dict, kids types.Array, index int, kid types.Object, refs map[int]annotationRef, depth int) error {
+	o, err := ctx.Dereference(kid)
+	if err != nil {
+		return err
+	}
+	d, ok := o.(types.Dict)
+	if !ok {
+		return nil
+	}
+
+	if t := d.Type(); t != nil && *t == "OBJR" {
+		// The structure tree root can not hold content.
+		if obj, ok := d["Obj"].(types.IndirectRef); ok && depth > 0 {
+			refs[obj.ObjectNumber.Value()] = annotationRef{elem: ref, elemDict: elem, kids: kids, index: index}
+		}
+		return nil
+	} else if t != nil && *t == "MCR" {
+		return nil
+	}
+
+	kidRef, _ := kid.(types.IndirectRef)
+	return walkStructElems(ctx, kidRef, d, refs, depth+1)
+}
+
+// flattenPageWidgets paints the appearances of the widgets after the page
+// content and removes the widgets. The widgets' object numbers are added
+// to free.
+func flattenPageWidgets(ctx *model.Context, page int, str types.Dict, refs map[int]annotationRef, free map[int]bool) error {
+	d, pageRef, inh, err := ctx.PageDict(page, false)
+	if err != nil {
+		return err
+	}
+	annots, err := ctx.DereferenceArray(d["Annots"])
+	if err != nil || len(annots) == 0 {
+		return err
+	}
+
+	// Marked content of the page is mapped to its structure
+	// elements by the parent tree.
+	var (
+		parents      types.Array
+		parentsNums  types.Array
+		parentsIndex = -1
+	)
+	if str != nil {
+		key, err := ctx.DereferenceInteger(d["StructParents"])
+		if err != nil {
+			return err
+		}
+		tree, err := ctx.DereferenceDict(str["ParentTree"])
+		if err != nil {
+			return err
+		}
+		if key != nil && tree != nil {
+			parentsNums, parentsIndex, err = numberTreeEntry(ctx, tree, key.Value(), 0)
+			if err != nil {
+				return err
+			}
+			if parentsIndex >= 0 {
+				parents, err = ctx.DereferenceArray(parentsNums[parentsIndex])
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	var (
+		content  strings.Builder
+		xobjects types.Dict
+		kept     = types.Array{}
+		marked   bool
+	)
+	for _, o := range annots {
+		a, err := ctx.DereferenceDict(o)
+		if err != nil {
+			return err
+		} else if st := a.NameEntry("Subtype"); st == nil || *st != "Widget" {
+			kept = append(kept, o)
+			continue
+		}
+
+		ref, isRef := o.(types.IndirectRef)
+		if isRef {
+			free[ref.ObjectNumber.Value()] = true
+		}
+
+		ap, ok, err := annotationAppearance(ctx, a)
+		if err != nil {
+			return err
+		}
+		var matrix string
+		if ok {
+			matrix, ok, err = appearanceMatrix(ctx, a, ap)
+			if err != nil {
+				return err
+			}
+		}
+
+		// The structure element references the content instead of the widget.
+		var sr annotationRef
+		if isRef {
+			sr = refs[ref.ObjectNumber.Value()]
+		}
+		var mcr types.Object
+		if sr.elemDict != nil && ok && sr.elem.ObjectNumber != 0 && parentsIndex >= 0 {
+			mcr = types.Dict{
+				"Type": types.Name("MCR"),
+				"Pg":   *pageRef,
+				"MCID": types.Integer(len(parents)),
+			}
+			parents = append(parents, sr.elem)
+			marked = true
+		}
+		if sr.elemDict != nil {
+			if sr.kids == nil {
+				if mcr != nil {
+					sr.elemDict["K"] = mcr
+				} else {
+					sr.elemDict.Delete("K")
+				}
+			} else {
+				// Removed references are replaced by null to keep the indexes valid.
+				sr.kids[sr.index] = mcr
+			}
+		}
+		if !ok {
+			continue
+		}
+
+		if xobjects == nil {
+			xobjects, err = pageXObjects(ctx, d, inh)
+			if err != nil {
+				return err
+			}
+		}
+		name := uniqueResourceName(xobjects, "FlatField")
+		xobjects[name] = ap
+
+		switch {
+		case mcr != nil:
+			tag := "Form"
+			if s := sr.elemDict.NameEntry("S"); s != nil {
+				tag = *s
+			}
+			fmt.Fprintf(&content, "/%s <</MCID %d>> BDC q %s cm /%s Do Q EMC\n", tag, len(parents)-1, matrix, name)
+		case str != nil:
+			fmt.Fprintf(&content, "/Artifact BMC q %s cm /%s Do Q EMC\n", matrix, name)
+		default:
+			fmt.Fprintf(&content, "q %s cm /%s Do Q\n", matrix, name)
+		}
+	}
+
+	if len(kept) > 0 {
+		d["Annots"] = kept
+	} else {
+		d.Delete("Annots")
+	}
+	if marked {
+		err = setObject(ctx, parentsNums, parentsIndex, parents)
+		if err != nil {
+			return err
+		}
+	}
+	if content.Len() == 0 {
+		return nil
+	}
+	return wrapPageContent(ctx, d, content.String())
+}
+
+// numberTreeEntry returns the Nums array of the number tree node holding
+// the key and the index of its value. The index is -1 if the key is missing.
+func numberTreeEntry(ctx *model.Context, node types.Dict, key, depth int) (types.Array, int, error) {
+	// Limit the depth to protect against cyclic references.
+	if depth >= 32 {
+		return nil, -1, nil
+	}
+
+	nums, err := ctx.DereferenceArray(node["Nums"])
+	if err != nil {
+		return nil, -1, err
+	}
+	for i := 0; i+1 < len(nums); i += 2 {
+		k, err := ctx.DereferenceInteger(nums[i])
+		if err != nil {
+			return nil, -1, err
+		} else if k != nil && k.Value() == key {
+			return nums, i + 1, nil
+		}
+	}
+
+	kids, err := ctx.DereferenceArray(node["Kids"])
+	if err != nil {
+		return nil, -1, err
+	}
+	for _, k := range kids {
+		kid, err := ctx.DereferenceDict(k)
+		if err != nil {
+			return nil, -1, err
+		} else if kid == nil {
+			continue
+		}
+		if limits, err := ctx.DereferenceArray(kid["Limits"]); err == nil && len(limits) == 2 {
+			lo, err1 := ctx.DereferenceInteger(limits[0])
+			hi, err2 := ctx.DereferenceInteger(limits[1])
+			if err1 == nil && err2 == nil && lo != nil && hi != nil && (key < lo.Value() || key > hi.Value()) {
+				continue
+			}
+		}
+		nums, index, err := numberTreeEntry(ctx, kid, key, depth+1)
+		if err != nil || index >= 0 {
+			return nums, index, err
+		}
+	}
+	return nil, -1, nil
+}
+
+// setObject sets the array element at index. Indirect elements
+// are replaced within the cross reference table.
+func setObject(ctx *model.Context, arr types.Array, index int, o types.Object) error {
+	ref, ok := arr[index].(types.IndirectRef)
+	if !ok {
+		arr[index] = o
+		return nil
+	}
+	entry, ok := ctx.FindTableEntryForIndRef(&ref)
+	if !ok {
+		return fmt.Errorf("invalid object reference: %s", ref)
+	}
+	entry.Object = o
+	return nil
+}
@@ -0,0 +1,179 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Template is a form PDF with cached field metadata.
+// Loading a template reads the form fields once, so repeated fills
+// do not have to read them again. A Template is safe for concurrent use.
+type Template struct {
+	file   string
+	fields []Field
+	byName map[string]Field
+}
+
+// LoadTemplate reads the form fields of the form PDF file.
+// The InputPassword option is used for password protected PDF files and
+// TempDir for the intermediate files, the other options are ignored.
+// The pdftk utility is required.
+func LoadTemplate(formPDFFile string, options ...Options) (*Template, error) {
+	return LoadTemplateContext(context.Background(), formPDFFile, options...)
+}
+
+// LoadTemplateContext is like LoadTemplate, but the context is used to cancel
+// the spawned external processes.
+func LoadTemplateContext(ctx context.Context, formPDFFile string, options ...Options) (*Template, error) {
+	inputs, err := absExistingFiles([]string{formPDFFile})
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := GetFieldsContext(ctx, inputs[0], options...)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]Field, len(fields))
+	for _, f := range fields {
+		byName[f.Name] = f
+	}
+
+	return &Template{
+		file:   inputs[0],
+		fields: fields,
+		byName: byName,
+	}, nil
+}
+
+// File returns the absolute path of the form PDF file.
+func (t *Template) File() string {
+	return t.file
+}
+
+// Fields returns the form fields of the template.
+func (t *Template) Fields() []Field {
+	fields := make([]Field, len(t.fields))
+	copy(fields, t.fields)
+	return fields
+}
+
+// Field returns the form field with the given name.
+func (t *Template) Field(name string) (Field, bool) {
+	f, ok := t.byName[name]
+	return f, ok
+}
+
+// Validate checks the form values against the template fields.
+// An error listing all problems is returned if a key does not match
+// a field or if a value is not one of the options of a button or
+// a non editable choice field.
+func (t *Template) Validate(form Form) error {
+	keys := make([]string, 0, len(form))
+	for key := range form {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var problems []string
+	for _, key := range keys {
+		f, ok := t.byName[key]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("unknown field '%s'", key))
+			continue
+		}
+
+		if !f.acceptsValue(form[key]) {
+			problems = append(problems, fmt.Sprintf("invalid value '%v' for field '%s': expected one of %s",
+				form[key], key, strings.Join(f.Options, ", ")))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid form: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// MissingRequired returns the names of the required fields
+// which stay empty if the template is filled with the form.
+func (t *Template) MissingRequired(form Form) []string {
+	return missingRequiredFields(form, t.fields)
+}
+
+// Fill fills the template with the form values like Fill.
+// The cached fields map bool values to the checkbox export values,
+// therefore the fields are not read again.
+func (t *Template) Fill(form Form, destPDFFile string, options ...Options) error {
+	return t.FillContext(context.Background(), form, destPDFFile, options...)
+}
+
+// FillContext is like Fill, but the context is used to cancel
+// the fill process and the spawned external processes.
+func (t *Template) FillContext(ctx context.Context, form Form, destPDFFile string, options ...Options) error {
+	if hasBoolValues(form) {
+		form = resolveButtonValues(form, t.fields)
+	}
+	return FillContext(ctx, form, t.file, destPDFFile, options...)
+}
+
+// acceptsValue returns whether the value is valid for the field.
+// Only button and non editable choice fields with options are checked.
+func (f Field) acceptsValue(value interface{}) bool {
+	if len(f.Options) == 0 {
+		return true
+	}
+
+	switch f.Type {
+	case fieldTypeButton:
+		if _, ok := value.(bool); ok {
+			return true
+		}
+	case fieldTypeChoice:
+		if f.Flags.Edit {
+			return true
+		}
+		if values, ok := value.([]string); ok {
+			for _, v := range values {
+				if !f.hasOption(v) {
+					return false
+				}
+			}
+			return true
+		}
+	default:
+		return true
+	}
+
+	return f.hasOption(formatValue(value))
+}
+
+func (f Field) hasOption(value string) bool {
+	for _, o := range f.Options {
+		if o == value {
+			return true
+		}
+	}
+	return value == buttonOffState && f.Type == fieldTypeButton
+}
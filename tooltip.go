@@ -0,0 +1,111 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// SetTooltips sets the tooltips of the fields of the input PDF file and
+// writes the result to the destination file. The tooltips map the fully
+// qualified field names to their alternate names (TU), which are shown
+// as tooltip and read by screen readers. An empty tooltip removes it.
+// An UnknownFieldsError is returned if a field does not exist.
+// An existing destination file is replaced. No external utility is required.
+func SetTooltips(inputPDFFile, destPDFFile string, tooltips map[string]string) error {
+	return SetTooltipsContext(context.Background(), inputPDFFile, destPDFFile, tooltips)
+}
+
+// SetTooltipsContext is like SetTooltips, but checks the context
+// for cancellation before processing.
+func SetTooltipsContext(ctx context.Context, inputPDFFile, destPDFFile string, tooltips map[string]string) (err error) {
+	// Get the absolute paths.
+	destPDFFile, err = filepath.Abs(destPDFFile)
+	if err != nil {
+		return fmt.Errorf("failed to create the absolute path: %w", err)
+	}
+	inputs, err := absExistingFiles([]string{inputPDFFile})
+	if err != nil {
+		return err
+	}
+
+	err = ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	// Create a temporary directory and remove it on defer again.
+	tmpDir, err := createTempDir("")
+	if err != nil {
+		return err
+	}
+	defer removeTempDir(tmpDir)
+
+	outputFile := filepath.Join(tmpDir, "output.pdf")
+	err = setTooltips(inputs[0], outputFile, tooltips)
+	if err != nil {
+		return err
+	}
+
+	return writeDestFile(outputFile, destPDFFile, true)
+}
+
+func setTooltips(inputFile, outputFile string, tooltips map[string]string) error {
+	ctx, err := readPdfcpuContext(inputFile, "")
+	if err != nil {
+		return err
+	}
+
+	found := make(map[string]bool, len(tooltips))
+	err = walkFields(ctx, func(name string, d types.Dict) error {
+		tooltip, ok := tooltips[name]
+		if !ok {
+			return nil
+		}
+		found[name] = true
+
+		if tooltip == "" {
+			d.Delete("TU")
+		} else {
+			d["TU"] = textString(tooltip)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set tooltips: %w", err)
+	}
+
+	var unknown []string
+	for name := range tooltips {
+		if !found[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return &UnknownFieldsError{Keys: unknown}
+	}
+
+	return writePdfcpuContext(ctx, outputFile)
+}
@@ -0,0 +1,85 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"context"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of the spans.
+const tracerName = "github.com/desertbit/fillpdf"
+
+// tracer returns the tracer of the TracerProvider option or
+// of the global OpenTelemetry tracer provider.
+func (o Options) tracer() trace.Tracer {
+	tp := o.TracerProvider
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return tp.Tracer(tracerName)
+}
+
+// startSpan starts a child span of the span within the context.
+// Spans are only recorded if the context carries a recording span,
+// which is started by the traced entry points.
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	tracer := trace.SpanFromContext(ctx).TracerProvider().Tracer(tracerName)
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan records the error, if any, and ends the span.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// setFileSize sets the size of the file as span attribute.
+// Files which can not be inspected are skipped.
+func setFileSize(span trace.Span, key, path string) {
+	if !span.IsRecording() {
+		return
+	}
+	fi, err := os.Stat(path)
+	if err == nil {
+		span.SetAttributes(attribute.Int64(key, fi.Size()))
+	}
+}
+
+// tracedPass wraps the pass with a span.
+func tracedPass(name string, p pass) pass {
+	return func(ctx context.Context, tmpDir, inputFile, outputFile string) (err error) {
+		ctx, span := startSpan(ctx, "fillpdf.pass "+name)
+		defer func() { endSpan(span, err) }()
+
+		err = p(ctx, tmpDir, inputFile, outputFile)
+		if err == nil {
+			setFileSize(span, "fillpdf.output.bytes", outputFile)
+		}
+		return err
+	}
+}
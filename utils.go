@@ -20,11 +20,18 @@ package fillpdf
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // exists returns whether the given file or directory exists or not
@@ -39,6 +46,73 @@ func exists(path string) (bool, error) {
 	return false, err
 }
 
+// absExistingFiles returns the absolute paths of the PDF files
+// and checks whether they exist.
+func absExistingFiles(paths []string) ([]string, error) {
+	abs := make([]string, len(paths))
+	for i, p := range paths {
+		p, err := filepath.Abs(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the absolute path: %w", err)
+		}
+
+		e, err := exists(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check if PDF file exists: %w", err)
+		} else if !e {
+			return nil, fmt.Errorf("%w: '%s'", ErrTemplateNotFound, p)
+		}
+		abs[i] = p
+	}
+	return abs, nil
+}
+
+// createTempDir creates a new temporary directory with an unpredictable
+// name within the parent directory. The directory is only accessible
+// by the current user. The parent defaults to the system's temporary
+// directory if empty.
+func createTempDir(parent string) (string, error) {
+	dir, err := ioutil.TempDir(parent, "fillpdf-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	return dir, nil
+}
+
+// removeTempDir removes the temporary directory again.
+// Errors are logged only.
+func removeTempDir(dir string) {
+	err := os.RemoveAll(dir)
+	if err != nil {
+		log.Printf("fillpdf: failed to remove temporary directory '%s' again: %v", dir, err)
+	}
+}
+
+// writeDestFile copies the output file to the destination file.
+// An existing destination file is only replaced if overwrite is set.
+func writeDestFile(outputFile, destFile string, overwrite bool) error {
+	// Check if the destination file exists.
+	e, err := exists(destFile)
+	if err != nil {
+		return fmt.Errorf("failed to check if destination PDF file exists: %w", err)
+	} else if e {
+		if !overwrite {
+			return fmt.Errorf("%w: '%s'", ErrDestinationExists, destFile)
+		}
+
+		err = os.Remove(destFile)
+		if err != nil {
+			return fmt.Errorf("failed to remove destination PDF file: %w", err)
+		}
+	}
+
+	err = copyFile(outputFile, destFile)
+	if err != nil {
+		return fmt.Errorf("failed to copy created output PDF to final destination: %w", err)
+	}
+	return nil
+}
+
 // copyFile copies the contents of the file named src to the file named
 // by dst. The file will be created if it does not already exist. If the
 // destination file exists, all it's contents will be replaced by the contents
@@ -66,21 +140,95 @@ func copyFile(src, dst string) (err error) {
 	return
 }
 
+// createFile creates or truncates the file named path.
+// The file is only accessible by the current user.
+func createFile(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+}
+
+// writeFile creates the file named path and writes the contents read from r to it.
+// The file is only accessible by the current user.
+func writeFile(path string, r io.Reader) (err error) {
+	out, err := createFile(path)
+	if err != nil {
+		return
+	}
+	defer func() {
+		cerr := out.Close()
+		if err == nil {
+			err = cerr
+		}
+	}()
+	_, err = io.Copy(out, r)
+	return
+}
+
 // runCommandInPath runs a command and waits for it to exit.
 // The working directory is also set.
 // The stderr error message is returned on error.
-func runCommandInPath(dir, name string, args ...string) error {
+// The process is killed if the context is done before it exits.
+func runCommandInPath(ctx context.Context, dir, name string, args ...string) error {
+	_, err := runCommandOutputInPath(ctx, dir, name, args...)
+	return err
+}
+
+// lookInstallPath returns the first executable of the install paths
+// or notFound if none exists.
+func lookInstallPath(paths []string, notFound error) (string, error) {
+	for _, path := range paths {
+		path, err := exec.LookPath(path)
+		if err == nil {
+			return path, nil
+		}
+	}
+	return "", notFound
+}
+
+// runCommandOutputInPath is like runCommandInPath, but returns
+// the stdout output of the command.
+func runCommandOutputInPath(ctx context.Context, dir, name string, args ...string) ([]byte, error) {
+	// Trace the command without its arguments, which may contain passwords.
+	base := filepath.Base(name)
+	ctx, span := startSpan(ctx, "fillpdf.exec "+base, attribute.String("process.executable.name", base))
+
 	// Create the command.
-	var stderr bytes.Buffer
-	cmd := exec.Command(name, args...)
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 	cmd.Dir = dir
 
 	// Start the command and wait for it to exit.
+	start := time.Now()
 	err := cmd.Run()
+
+	exitCode := -1
+	if cmd.ProcessState != nil {
+		exitCode = cmd.ProcessState.ExitCode()
+	}
+	duration := time.Since(start)
+	logCommand(ctx, name, duration, exitCode, err)
+	observeCommand(ctx, name, duration, exitCode)
+
+	span.SetAttributes(
+		attribute.Int("process.exit.code", exitCode),
+		attribute.Int("fillpdf.output.bytes", stdout.Len()),
+	)
+	endSpan(span, err)
+
 	if err != nil {
-		return fmt.Errorf(strings.TrimSpace(stderr.String()))
+		// The stderr output of a killed process is meaningless.
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, &ExecError{
+			Name:     name,
+			Args:     redactArgs(args),
+			Stderr:   strings.TrimSpace(stderr.String()),
+			ExitCode: exitCode,
+			Err:      err,
+		}
 	}
 
-	return nil
+	return stdout.Bytes(), nil
 }
@@ -0,0 +1,214 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTimeLayout is the layout of time values if no layout is set.
+const DefaultTimeLayout = "2006-01-02"
+
+// FieldValuer is implemented by types controlling their own form value
+// representation, analogous to encoding.TextMarshaler.
+// Without it, values are formatted with fmt's %v verb.
+type FieldValuer interface {
+	FieldValue() (string, error)
+}
+
+// encodeValues returns the form with the values converted to their
+// textual representation where the value or the options define one,
+// e.g. the value encoder, FieldValuer and time values.
+// The form is only copied if a value changes.
+func (o Options) encodeValues(form Form) (Form, error) {
+	var encoded Form
+	for key, value := range form {
+		s, ok, err := o.encodeValue(key, value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode value of field '%s': %w", key, err)
+		} else if !ok {
+			continue
+		}
+
+		if encoded == nil {
+			encoded = make(Form, len(form))
+			for k, v := range form {
+				encoded[k] = v
+			}
+		}
+		encoded[key] = s
+	}
+
+	if encoded == nil {
+		return form, nil
+	}
+	return encoded, nil
+}
+
+// encodeValue returns the textual representation of the value
+// if the value or the options define one.
+func (o Options) encodeValue(key string, value interface{}) (string, bool, error) {
+	if o.ValueEncoder != nil {
+		switch value.(type) {
+		case Image, SignatureImage, imageEncoder:
+			return "", false, nil
+		}
+		s, err := o.ValueEncoder(key, value)
+		return s, true, err
+	}
+
+	switch v := value.(type) {
+	case FieldValuer:
+		s, err := v.FieldValue()
+		return s, true, err
+	case time.Time:
+		return o.formatTime(key, v), true, nil
+	case *time.Time:
+		if v == nil {
+			return "", true, nil
+		}
+		return o.formatTime(key, *v), true, nil
+	}
+	return "", false, nil
+}
+
+// formatTime formats the time with the layout of the field.
+// The zero time is formatted as empty string.
+func (o Options) formatTime(key string, t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	layout := o.FieldTimeLayouts[key]
+	if layout == "" {
+		layout = o.TimeLayout
+	}
+	if layout == "" {
+		layout = DefaultTimeLayout
+	}
+	return t.Format(layout)
+}
+
+// prepareForm flattens nested maps, expands repeated values and
+// translates the checkbox values.
+func (o Options) prepareForm(form Form) (Form, error) {
+	form, err := flattenForm(form)
+	if err != nil {
+		return nil, err
+	}
+	form, err = expandRepeated(form, o.RepeatPattern)
+	if err != nil {
+		return nil, err
+	}
+	return o.mapCheckboxes(form), nil
+}
+
+// expandRepeated returns the form with slice values expanded to numbered
+// fields named by the pattern. Nothing is expanded if the pattern is empty.
+// The form is only copied if it contains slice values.
+func expandRepeated(form Form, pattern string) (Form, error) {
+	if pattern == "" {
+		return form, nil
+	} else if !strings.Contains(pattern, "{index}") {
+		return nil, fmt.Errorf("invalid repeat pattern '%s': missing {index} placeholder", pattern)
+	}
+
+	var expanded Form
+	for key, value := range form {
+		rv := reflect.ValueOf(value)
+		if rv.Kind() != reflect.Slice || rv.Type().Elem().Kind() == reflect.Uint8 {
+			continue
+		}
+
+		if expanded == nil {
+			expanded = make(Form, len(form))
+			for k, v := range form {
+				expanded[k] = v
+			}
+		}
+		delete(expanded, key)
+
+		for i := 0; i < rv.Len(); i++ {
+			name := strings.NewReplacer("{name}", key, "{index}", strconv.Itoa(i+1)).Replace(pattern)
+			if _, ok := expanded[name]; ok {
+				return nil, fmt.Errorf("duplicate form value for field '%s'", name)
+			}
+			expanded[name] = rv.Index(i).Interface()
+		}
+	}
+
+	if expanded == nil {
+		return form, nil
+	}
+	return expanded, nil
+}
+
+// flattenForm returns the form with nested maps flattened to dotted
+// field names, e.g. {"applicant": {"name": "x"}} to {"applicant.name": "x"}.
+// The form is only copied if it contains nested maps.
+func flattenForm(form Form) (Form, error) {
+	nested := false
+	for _, value := range form {
+		switch value.(type) {
+		case map[string]interface{}, Form:
+			nested = true
+		}
+	}
+	if !nested {
+		return form, nil
+	}
+
+	flat := make(Form, len(form))
+	err := flattenNested(flat, "", form)
+	if err != nil {
+		return nil, err
+	}
+	return flat, nil
+}
+
+func flattenNested(flat Form, prefix string, m map[string]interface{}) error {
+	for key, value := range m {
+		name := prefix + key
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			err := flattenNested(flat, name+".", v)
+			if err != nil {
+				return err
+			}
+			continue
+		case Form:
+			err := flattenNested(flat, name+".", v)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if _, ok := flat[name]; ok {
+			return fmt.Errorf("duplicate form value for field '%s'", name)
+		}
+		flat[name] = value
+	}
+	return nil
+}
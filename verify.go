@@ -0,0 +1,142 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// verifyFilled reparses the filled PDF file and checks that the fields
+// contain the form values. Values of unknown fields are skipped.
+// The values are not checked if the fields were removed by flattening.
+func verifyFilled(form Form, pdfFile, password string, flattened bool) error {
+	ctx, err := readPdfcpuContext(pdfFile, password)
+	if err != nil {
+		return fmt.Errorf("failed to verify filled PDF: %w", err)
+	}
+
+	f, err := acroForm(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to verify filled PDF: %w", err)
+	} else if f == nil {
+		if flattened || len(form) == 0 {
+			return nil
+		}
+		return fmt.Errorf("failed to verify filled PDF: document has no form")
+	}
+
+	var mismatched []string
+	err = walkFields(ctx, func(name string, d types.Dict) error {
+		value, ok := form[name]
+		if !ok {
+			return nil
+		}
+		got, err := valueTexts(ctx, d["V"])
+		if err != nil {
+			return err
+		}
+		if !valueMatches(value, got) {
+			mismatched = append(mismatched, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to verify filled PDF: %w", err)
+	}
+
+	if len(mismatched) > 0 {
+		sort.Strings(mismatched)
+		return &VerifyError{Fields: mismatched}
+	}
+	return nil
+}
+
+// valueTexts returns the text of the field value, which is
+// a name, a string or an array of strings for multiple values.
+func valueTexts(ctx *model.Context, o types.Object) ([]string, error) {
+	o, err := ctx.Dereference(o)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := o.(type) {
+	case nil:
+		return nil, nil
+	case types.Name:
+		return []string{string(v)}, nil
+	case types.Array:
+		var values []string
+		for _, e := range v {
+			s, err := valueTexts(ctx, e)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, s...)
+		}
+		return values, nil
+	default:
+		s, err := model.Text(o)
+		if err != nil {
+			return nil, err
+		}
+		return []string{s}, nil
+	}
+}
+
+// valueMatches returns whether the field values match the form value.
+// Line breaks are compared regardless of their style and empty
+// values match the off state of buttons.
+func valueMatches(value interface{}, got []string) bool {
+	var want []string
+	switch v := value.(type) {
+	case []string:
+		want = v
+	case bool:
+		checked := len(got) == 1 && got[0] != "" && got[0] != buttonOffState
+		return v == checked
+	default:
+		if s := formatValue(v); s != "" && s != buttonOffState {
+			want = []string{s}
+		}
+	}
+
+	if len(got) == 1 && (got[0] == "" || got[0] == buttonOffState) {
+		got = nil
+	}
+	if len(want) != len(got) {
+		return false
+	}
+	for i := range want {
+		if normalizeLineBreaks(want[i]) != normalizeLineBreaks(got[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+var newlineReplacer = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+func normalizeLineBreaks(s string) string {
+	return newlineReplacer.Replace(s)
+}
@@ -0,0 +1,428 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+const (
+	xfaDatasetsPacket = "datasets"
+	xfaDataNamespace  = "http://www.xfa.org/schema/xfa-data/1.0/"
+)
+
+var (
+	xfaDatasetsRegexp = regexp.MustCompile(`(?s)<xfa:datasets[\s>].*?</xfa:datasets>`)
+	xfaNameRegexp     = regexp.MustCompile(`^([^\[\]]+)(?:\[(\d+)\])?$`)
+)
+
+// dropXFA removes the XFA form of the PDF file, so only the AcroForm remains.
+func dropXFA(inputFile, outputFile, password string) error {
+	ctx, err := readPdfcpuContext(inputFile, password)
+	if err != nil {
+		return err
+	}
+
+	err = dropXFAContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return writePdfcpuContext(ctx, outputFile)
+}
+
+// dropXFAContext removes the XFA form from the pdfcpu context.
+func dropXFAContext(ctx *model.Context) error {
+	form, err := acroForm(ctx)
+	if err != nil {
+		return err
+	} else if form != nil {
+		form.Delete("XFA")
+	}
+
+	// Viewers must not render the dropped XFA form.
+	root, err := ctx.Catalog()
+	if err != nil {
+		return err
+	}
+	root.Delete("NeedsRendering")
+	return nil
+}
+
+// hasXFA returns whether the document contains an XFA form and
+// whether it contains AcroForm fields as well.
+func hasXFA(ctx *model.Context) (xfa, acroFields bool, err error) {
+	form, err := acroForm(ctx)
+	if err != nil || form == nil {
+		return
+	}
+
+	_, xfa = form.Find("XFA")
+
+	fields, err := ctx.DereferenceArray(form["Fields"])
+	if err != nil {
+		return
+	}
+	acroFields = len(fields) > 0
+	return
+}
+
+// checkNotXFAOnly returns an error if the form PDF file contains an XFA form
+// without AcroForm fields, which can not be filled with fill_form.
+// Documents which can not be inspected are not reported.
+func checkNotXFAOnly(formPDFFile, password string) error {
+	ctx, err := readPdfcpuContext(formPDFFile, password)
+	if err != nil {
+		return nil
+	}
+
+	xfa, acroFields, err := hasXFA(ctx)
+	if err == nil && xfa && !acroFields {
+		return fmt.Errorf("form PDF file is an XFA only form, enable the XFA option to fill it")
+	}
+	return nil
+}
+
+// fillXFA fills the XFA form by injecting the form values as
+// datasets packet into the XFA form.
+func fillXFA(ctx context.Context, form Form, formPDFFile, outputFile, password string) error {
+	// Filling is not cancelable, so check at least once.
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	datasets, err := xfaDatasets(form)
+	if err != nil {
+		return fmt.Errorf("failed to create XFA datasets: %w", err)
+	}
+
+	pctx, err := readPdfcpuContext(formPDFFile, password)
+	if err != nil {
+		return err
+	}
+
+	err = setXFADatasets(pctx, datasets)
+	if err != nil {
+		return fmt.Errorf("failed to set XFA datasets: %w", err)
+	}
+
+	return writePdfcpuContext(pctx, outputFile)
+}
+
+// setXFADatasets replaces the datasets packet of the XFA form.
+func setXFADatasets(ctx *model.Context, datasets []byte) error {
+	form, err := acroForm(ctx)
+	if err != nil {
+		return err
+	} else if form == nil {
+		return fmt.Errorf("document has no form")
+	}
+
+	obj, ok := form.Find("XFA")
+	if !ok {
+		return fmt.Errorf("document has no XFA form")
+	}
+	obj, err = ctx.Dereference(obj)
+	if err != nil {
+		return err
+	}
+
+	switch xfa := obj.(type) {
+	case types.Array:
+		// The XFA form is split into packets: [name1 stream1 name2 stream2 ...].
+		ref, err := newStream(ctx, datasets)
+		if err != nil {
+			return err
+		}
+
+		packets := append(types.Array{}, xfa...)
+		for i := 0; i+1 < len(packets); i += 2 {
+			name, err := ctx.DereferenceText(packets[i])
+			if err != nil {
+				return err
+			}
+			if name == xfaDatasetsPacket {
+				packets[i+1] = ref
+				form["XFA"] = packets
+				return nil
+			}
+		}
+
+		// Insert the datasets packet before the closing postamble.
+		pos := len(packets)
+		if pos >= 2 {
+			if name, err := ctx.DereferenceText(packets[pos-2]); err == nil && name == "postamble" {
+				pos -= 2
+			}
+		}
+		packets = append(packets[:pos], append(types.Array{types.StringLiteral(xfaDatasetsPacket), ref}, packets[pos:]...)...)
+		form["XFA"] = packets
+		return nil
+
+	case types.StreamDict:
+		// The XFA form is a single XML document.
+		err = xfa.Decode()
+		if err != nil {
+			return err
+		}
+
+		content := xfa.Content
+		if xfaDatasetsRegexp.Match(content) {
+			content = xfaDatasetsRegexp.ReplaceAllLiteral(content, datasets)
+		} else {
+			i := bytes.LastIndex(content, []byte("</xdp:xdp>"))
+			if i < 0 {
+				return fmt.Errorf("invalid XFA form: missing xdp:xdp element")
+			}
+			content = append(append(append([]byte{}, content[:i]...), datasets...), content[i:]...)
+		}
+
+		ref, err := newStream(ctx, content)
+		if err != nil {
+			return err
+		}
+		form["XFA"] = ref
+		return nil
+
+	default:
+		return fmt.Errorf("invalid XFA form type: %T", obj)
+	}
+}
+
+// newStream adds a new flate encoded stream with the content to the document.
+func newStream(ctx *model.Context, content []byte) (types.IndirectRef, error) {
+	sd, err := ctx.NewStreamDictForBuf(content)
+	if err != nil {
+		return types.IndirectRef{}, err
+	}
+	err = sd.Encode()
+	if err != nil {
+		return types.IndirectRef{}, err
+	}
+	ref, err := ctx.IndRefForNewObject(*sd)
+	if err != nil {
+		return types.IndirectRef{}, err
+	}
+	return *ref, nil
+}
+
+// xfaNode is an element of the XFA data tree.
+type xfaNode struct {
+	name     string
+	value    *string
+	children []*xfaNode
+}
+
+// child returns the index-th child element with the name.
+// Missing elements are created.
+func (n *xfaNode) child(name string, index int) *xfaNode {
+	var c *xfaNode
+	for _, cn := range n.children {
+		if cn.name != name {
+			continue
+		} else if index == 0 {
+			return cn
+		}
+		index--
+		c = cn
+	}
+	for ; index >= 0; index-- {
+		c = &xfaNode{name: name}
+		n.children = append(n.children, c)
+	}
+	return c
+}
+
+func (n *xfaNode) encode(e *xml.Encoder) error {
+	start := xml.StartElement{Name: xml.Name{Local: n.name}}
+	err := e.EncodeToken(start)
+	if err != nil {
+		return err
+	}
+	if n.value != nil {
+		err = e.EncodeToken(xml.CharData(*n.value))
+		if err != nil {
+			return err
+		}
+	}
+	for _, c := range n.children {
+		err = c.encode(e)
+		if err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// xfaDatasets returns the XFA datasets packet containing the form values.
+// The dotted field names describe the data element hierarchy starting
+// with the root subform, e.g. "form1.Page1.Name". Repeated elements are
+// addressed with an index, e.g. "form1.Item[1].Price".
+func xfaDatasets(form Form) ([]byte, error) {
+	keys := make([]string, 0, len(form))
+	for key := range form {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	data := &xfaNode{name: "xfa:data"}
+	for _, key := range keys {
+		n := data
+		for _, part := range strings.Split(key, ".") {
+			m := xfaNameRegexp.FindStringSubmatch(part)
+			if m == nil {
+				return nil, fmt.Errorf("invalid XFA field name: '%s'", key)
+			}
+			index := 0
+			if m[2] != "" {
+				index, _ = strconv.Atoi(m[2])
+			}
+			n = n.child(m[1], index)
+		}
+
+		value := xfaValue(form[key])
+		n.value = &value
+	}
+
+	var b bytes.Buffer
+	b.WriteString(`<xfa:datasets xmlns:xfa="` + xfaDataNamespace + `">`)
+	e := xml.NewEncoder(&b)
+	err := data.encode(e)
+	if err != nil {
+		return nil, err
+	}
+	err = e.Flush()
+	if err != nil {
+		return nil, err
+	}
+	b.WriteString("</xfa:datasets>")
+	return b.Bytes(), nil
+}
+
+// xfaValue returns the XFA data value. Booleans are mapped to
+// the default on and off values of XFA checkboxes.
+func xfaValue(value interface{}) string {
+	if b, ok := value.(bool); ok {
+		if b {
+			return "1"
+		}
+		return "0"
+	}
+	return formatValue(value)
+}
+
+// syncXFAPass writes the values of the filled AcroForm fields into the
+// XFA datasets of hybrid documents. Other documents are left unchanged.
+func syncXFAPass(ctx context.Context, tmpDir, inputFile, outputFile string) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	err = syncXFA(inputFile, outputFile)
+	if err != nil {
+		return fmt.Errorf("failed to sync XFA datasets: %w", err)
+	}
+	return nil
+}
+
+func syncXFA(inputFile, outputFile string) error {
+	ctx, err := readPdfcpuContext(inputFile, "")
+	if err != nil {
+		return err
+	}
+
+	xfa, acroFields, err := hasXFA(ctx)
+	if err != nil {
+		return err
+	} else if !xfa || !acroFields {
+		return writePdfcpuContext(ctx, outputFile)
+	}
+
+	form, err := xfaFieldValues(ctx)
+	if err != nil {
+		return err
+	}
+	datasets, err := xfaDatasets(form)
+	if err != nil {
+		return err
+	}
+	err = setXFADatasets(ctx, datasets)
+	if err != nil {
+		return err
+	}
+
+	return writePdfcpuContext(ctx, outputFile)
+}
+
+// xfaFieldValues returns the values of the AcroForm fields mapped by
+// their XFA data names. Checkboxes in the off state map to the XFA
+// off value and multiple values are separated by line breaks.
+func xfaFieldValues(ctx *model.Context) (Form, error) {
+	form := make(Form)
+	err := walkFields(ctx, func(name string, d types.Dict) error {
+		v, ok := d.Find("V")
+		if !ok {
+			return nil
+		}
+		dataName := xfaDataName(name)
+		if dataName == "" {
+			return nil
+		}
+
+		values, err := valueTexts(ctx, v)
+		if err != nil {
+			return err
+		}
+		if len(values) == 1 && values[0] == buttonOffState {
+			values[0] = "0"
+		}
+		form[dataName] = strings.Join(values, "\n")
+		return nil
+	})
+	return form, err
+}
+
+// xfaDataName returns the XFA data name of the AcroForm field name of
+// a hybrid document, e.g. "form1[0].#subform[0].Name[0]" is mapped to
+// "form1[0].Name[0]". Unnamed subforms bind no data and are skipped.
+// An empty string is returned if the name can not be mapped.
+func xfaDataName(name string) string {
+	var parts []string
+	for _, part := range strings.Split(name, ".") {
+		if strings.HasPrefix(part, "#") {
+			continue
+		} else if !xfaNameRegexp.MatchString(part) {
+			return ""
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,215 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// XFAData is the data of an XFA form.
+type XFAData struct {
+	// XML is the XFA datasets packet. It is empty if the
+	// XFA form contains no data.
+	XML []byte
+	// Values are the values of the data elements mapped by their dotted
+	// names as accepted by the XFA option, e.g. "form1.Page1.Name".
+	// Repeated elements are addressed with an index, e.g. "form1.Item[1].Price".
+	Values map[string]string
+}
+
+// GetXFAData returns the data of the XFA form of the PDF file, e.g. to read
+// the values of XFA only forms. Only data elements without child elements
+// are contained in the values. The InputPassword option is used for
+// password protected PDF files, the other options are ignored.
+func GetXFAData(pdfFile string, options ...Options) (*XFAData, error) {
+	return GetXFADataContext(context.Background(), pdfFile, options...)
+}
+
+// GetXFADataContext is like GetXFAData, but checks the context
+// for cancellation before processing.
+func GetXFADataContext(ctx context.Context, pdfFile string, options ...Options) (*XFAData, error) {
+	opts := getOptions(options)
+
+	err := ctx.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	inputs, err := absExistingFiles([]string{pdfFile})
+	if err != nil {
+		return nil, err
+	}
+
+	pdfCtx, err := readPdfcpuContext(inputs[0], opts.InputPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	datasets, err := xfaDatasetsXML(pdfCtx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read XFA datasets: %w", err)
+	}
+
+	values, err := parseXFAData(datasets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse XFA datasets: %w", err)
+	}
+	return &XFAData{XML: datasets, Values: values}, nil
+}
+
+// xfaDatasetsXML returns the datasets packet of the XFA form.
+// Nil is returned if the XFA form has no datasets packet.
+func xfaDatasetsXML(ctx *model.Context) ([]byte, error) {
+	form, err := acroForm(ctx)
+	if err != nil {
+		return nil, err
+	} else if form == nil {
+		return nil, fmt.Errorf("document has no form")
+	}
+
+	obj, ok := form.Find("XFA")
+	if !ok {
+		return nil, fmt.Errorf("document has no XFA form")
+	}
+	obj, err = ctx.Dereference(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	switch xfa := obj.(type) {
+	case types.Array:
+		for i := 0; i+1 < len(xfa); i += 2 {
+			name, err := ctx.DereferenceText(xfa[i])
+			if err != nil {
+				return nil, err
+			} else if name != xfaDatasetsPacket {
+				continue
+			}
+
+			sd, _, err := ctx.DereferenceStreamDict(xfa[i+1])
+			if err != nil {
+				return nil, err
+			} else if sd == nil {
+				return nil, nil
+			}
+			err = sd.Decode()
+			if err != nil {
+				return nil, err
+			}
+			return sd.Content, nil
+		}
+		return nil, nil
+
+	case types.StreamDict:
+		err = xfa.Decode()
+		if err != nil {
+			return nil, err
+		}
+		return xfaDatasetsRegexp.Find(xfa.Content), nil
+
+	default:
+		return nil, fmt.Errorf("invalid XFA form type: %T", obj)
+	}
+}
+
+// xfaDataElement is an open element while parsing the XFA data.
+type xfaDataElement struct {
+	name     string
+	text     strings.Builder
+	children map[string]int
+	hasChild bool
+}
+
+// parseXFAData returns the values of the data elements of the datasets
+// packet mapped by their dotted names. The first of repeated elements
+// has no index, like with xfaDatasets.
+func parseXFAData(datasets []byte) (map[string]string, error) {
+	values := make(map[string]string)
+	if len(datasets) == 0 {
+		return values, nil
+	}
+
+	var (
+		d     = xml.NewDecoder(bytes.NewReader(datasets))
+		stack []*xfaDataElement // Elements outside of xfa:data are nil.
+		data  = -1              // Depth of the xfa:data element.
+	)
+	for {
+		t, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch t := t.(type) {
+		case xml.StartElement:
+			if data < 0 {
+				var e *xfaDataElement
+				if t.Name.Space == xfaDataNamespace && t.Name.Local == "data" {
+					data = len(stack)
+					e = &xfaDataElement{children: make(map[string]int)}
+				}
+				stack = append(stack, e)
+				continue
+			}
+
+			parent := stack[len(stack)-1]
+			name := t.Name.Local
+			if index := parent.children[name]; index > 0 {
+				name = fmt.Sprintf("%s[%d]", name, index)
+			}
+			parent.children[t.Name.Local]++
+			parent.hasChild = true
+			if parent.name != "" {
+				name = parent.name + "." + name
+			}
+			stack = append(stack, &xfaDataElement{name: name, children: make(map[string]int)})
+
+		case xml.CharData:
+			if data >= 0 {
+				stack[len(stack)-1].text.Write(t)
+			}
+
+		case xml.EndElement:
+			if len(stack) == 0 {
+				continue
+			}
+			e := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if data < 0 {
+				continue
+			} else if len(stack) == data {
+				data = -1
+			} else if !e.hasChild {
+				values[e.name] = e.text.String()
+			}
+		}
+	}
+	return values, nil
+}
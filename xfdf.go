@@ -0,0 +1,130 @@
+/*
+ *  FillPDF - Fill PDF forms
+ *  Copyright DesertBit
+ *  Author: Roland Singer
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package fillpdf
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"sort"
+	"strings"
+)
+
+const xfdfHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<xfdf xmlns="http://ns.adobe.com/xfdf/" xml:space="preserve">
+<fields>`
+
+const xfdfFooter = `</fields>
+</xfdf>`
+
+// GenerateFDF returns the fdf form data file passed to pdftk without
+// filling a form, e.g. to inspect or archive the form data.
+// The Encoding and time layout options are used, the other options are ignored.
+// Image values are skipped, since images are stamped onto the pages.
+// Bool values are written as is, because their checkbox export values
+// are only known with the form PDF.
+func GenerateFDF(form Form, options ...Options) ([]byte, error) {
+	opts := getOptions(options)
+	form, err := opts.prepareForm(form)
+	if err != nil {
+		return nil, err
+	}
+	form, err = opts.encodeValues(form)
+	if err != nil {
+		return nil, err
+	}
+	form, _ = splitImages(form)
+
+	var buf bytes.Buffer
+	err = writeFdf(&buf, form, opts.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GenerateXFDF returns the form values as UTF-8 encoded XFDF data,
+// which is supported by pdftk and other tools. Image values are skipped
+// and bool values are written as is like with GenerateFDF.
+// The time layout options are used, the other options are ignored.
+func GenerateXFDF(form Form, options ...Options) ([]byte, error) {
+	opts := getOptions(options)
+	form, err := opts.prepareForm(form)
+	if err != nil {
+		return nil, err
+	}
+	form, err = opts.encodeValues(form)
+	if err != nil {
+		return nil, err
+	}
+	form, _ = splitImages(form)
+
+	var buf bytes.Buffer
+	err = writeXfdf(&buf, form)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeXfdf writes the form values as xfdf data to w.
+// The fields are written sorted by their fully qualified names.
+func writeXfdf(w io.Writer, form Form) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(xfdfHeader + "\n")
+
+	keys := make([]string, 0, len(form))
+	for key := range form {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		bw.WriteString(`<field name="`)
+		xml.EscapeText(bw, []byte(key))
+		bw.WriteString(`">`)
+
+		switch v := form[key].(type) {
+		case []string:
+			for _, s := range v {
+				writeXfdfValue(bw, s)
+			}
+		case RichText:
+			// The XHTML body is embedded without the XML declaration.
+			bw.WriteString("<value-richtext>")
+			bw.WriteString(strings.TrimPrefix(v.richValue(), `<?xml version="1.0"?>`))
+			bw.WriteString("</value-richtext>")
+			writeXfdfValue(bw, v.Value)
+		default:
+			writeXfdfValue(bw, formatValue(v))
+		}
+
+		bw.WriteString("</field>\n")
+	}
+
+	bw.WriteString(xfdfFooter + "\n")
+	return bw.Flush()
+}
+
+func writeXfdfValue(bw *bufio.Writer, value string) {
+	bw.WriteString("<value>")
+	xml.EscapeText(bw, []byte(value))
+	bw.WriteString("</value>")
+}